@@ -0,0 +1,132 @@
+package startwatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrFrontendWatcherMissing = errors.New("frontend watcher missing")
+	ErrEventsChannelMissing   = errors.New("events channel missing")
+)
+
+// Command models the Start Frontends Watcher Command which can be used to
+// watch for changes in the frontends configuration.
+type Command struct {
+	watcher interfaces.FrontendWatcher
+	logger  interfaces.Logger
+}
+
+// NewCommand creates a new Start Frontends Watcher Command using the provided
+// frontend watcher.
+func NewCommand(
+	watcher interfaces.FrontendWatcher,
+	logger interfaces.Logger) (*Command, error) {
+	if watcher == nil {
+		return nil, ErrFrontendWatcherMissing
+	}
+
+	return &Command{
+		watcher: watcher,
+		logger:  logger,
+	}, nil
+}
+
+// Execute runs the Start Frontends Watcher Command by subscribing to the
+// frontend watcher and pushing its events to the provided channel.
+func (c *Command) Execute(model *Model) error {
+	if model.Events == nil {
+		return ErrEventsChannelMissing
+	}
+
+	if model.Ctx == nil {
+		model.Ctx = context.Background()
+	}
+
+	if model.WaitGroup == nil {
+		model.WaitGroup = &sync.WaitGroup{}
+	}
+
+	c.logger.Info("subscribing to frontend watcher")
+
+	events := c.watcher.Subscribe()
+
+	model.WaitGroup.Add(1)
+
+	go c.run(model.Ctx, model.WaitGroup, events, model.Events)
+
+	return nil
+}
+
+func (c *Command) run(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	in <-chan interfaces.FrontendEvent,
+	out chan<- Event) {
+	defer wg.Done()
+	defer c.watcher.Unsubscribe(in)
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("context is done: returning")
+			return
+
+		case frontendEvent, ok := <-in:
+			if !ok {
+				c.logger.Info("frontend watcher closed: returning")
+				return
+			}
+
+			event, err := c.translate(frontendEvent)
+			if err != nil {
+				c.logger.
+					WithError(err).
+					WithField("name", frontendEvent.Name).
+					Error("describing frontend")
+
+				break
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				c.logger.Info("context is done: returning")
+				return
+			}
+		}
+	}
+}
+
+func (c *Command) translate(frontendEvent interfaces.FrontendEvent) (Event, error) {
+	event := Event{
+		Name:     frontendEvent.Name,
+		Kind:     frontendEvent.Kind,
+		Revision: frontendEvent.Revision,
+		Frontend: frontendEvent.Frontend,
+	}
+
+	// deleted events and events carrying a payload need no describe
+	if event.Kind == interfaces.EventDeleted || event.Frontend != nil {
+		return event, nil
+	}
+
+	frontend, err := c.watcher.DescribeFrontend(frontendEvent.Name)
+	if err != nil {
+		if err == interfaces.ErrUnknownFrontend {
+			event.Kind = interfaces.EventDeleted
+
+			return event, nil
+		}
+
+		return Event{}, err
+	}
+
+	event.Frontend = frontend
+
+	return event, nil
+}
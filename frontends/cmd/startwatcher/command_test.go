@@ -0,0 +1,219 @@
+package startwatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+func TestNewCommand(t *testing.T) {
+	c, err := NewCommand(newDummyWatcher(), logger)
+
+	assert.NotNil(t, c)
+	assert.Nil(t, err)
+}
+
+func TestNewCommandShouldReturnErrorOnMissingWatcher(t *testing.T) {
+	c, err := NewCommand(nil, logger)
+
+	assert.Nil(t, c)
+	assert.Equal(t, ErrFrontendWatcherMissing, err)
+}
+
+func TestExecuteShouldReturnErrorOnMissingEventsChannel(t *testing.T) {
+	c, _ := NewCommand(newDummyWatcher(), logger)
+
+	err := c.Execute(&Model{})
+
+	assert.Equal(t, ErrEventsChannelMissing, err)
+}
+
+func TestExecute(t *testing.T) {
+	w := newDummyWatcher("testapp")
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	events := make(chan Event)
+
+	err := c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    events,
+	})
+
+	assert.Nil(t, err)
+
+	w.events <- interfaces.FrontendEvent{Name: "testapp"}
+
+	event := <-events
+	assert.Equal(t, "testapp", event.Name)
+	assert.NotNil(t, event.Frontend)
+
+	w.events <- interfaces.FrontendEvent{Name: "unknown"}
+
+	event = <-events
+	assert.Equal(t, "unknown", event.Name)
+	assert.Equal(t, interfaces.EventDeleted, event.Kind)
+	assert.Nil(t, event.Frontend)
+
+	// describe errors are logged and not forwarded
+	w.events <- interfaces.FrontendEvent{Name: "fail"}
+	w.events <- interfaces.FrontendEvent{Name: "testapp"}
+
+	event = <-events
+	assert.Equal(t, "testapp", event.Name)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestExecuteShouldForwardKindRevisionAndPayload(t *testing.T) {
+	w := newDummyWatcher()
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	events := make(chan Event)
+
+	c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    events,
+	})
+
+	payload := mockFrontend("payload")
+
+	// the watcher does not know this frontend, so describing it would result
+	// in a deleted event
+	w.events <- interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Revision: 7,
+		Frontend: payload,
+	}
+
+	event := <-events
+	assert.Equal(t, interfaces.EventCreated, event.Kind)
+	assert.Equal(t, uint64(7), event.Revision)
+	assert.Equal(t, payload, event.Frontend)
+
+	w.events <- interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventDeleted,
+		Revision: 8,
+	}
+
+	event = <-events
+	assert.Equal(t, interfaces.EventDeleted, event.Kind)
+	assert.Equal(t, uint64(8), event.Revision)
+	assert.Nil(t, event.Frontend)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestExecuteShouldStopWhenBlockedOnOutput(t *testing.T) {
+	w := newDummyWatcher("testapp")
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    make(chan Event),
+	})
+
+	w.events <- interfaces.FrontendEvent{Name: "testapp"}
+
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	wg.Wait()
+	assert.True(t, w.unsubscribed)
+}
+
+func TestExecuteShouldStopWhenWatcherIsClosed(t *testing.T) {
+	w := newDummyWatcher()
+	c, _ := NewCommand(w, logger)
+
+	wg := &sync.WaitGroup{}
+
+	c.Execute(&Model{
+		WaitGroup: wg,
+		Events:    make(chan Event),
+	})
+
+	close(w.events)
+
+	wg.Wait()
+}
+
+type dummyWatcher struct {
+	frontendNames []string
+	events        chan interfaces.FrontendEvent
+	unsubscribed  bool
+}
+
+func newDummyWatcher(frontendNames ...string) *dummyWatcher {
+	return &dummyWatcher{
+		frontendNames: frontendNames,
+		events:        make(chan interfaces.FrontendEvent),
+	}
+}
+
+func (w *dummyWatcher) ListFrontends() ([]string, error) {
+	return w.frontendNames, nil
+}
+
+func (w *dummyWatcher) DescribeFrontend(name string) (*frontends.Frontend, error) {
+	if name == "fail" {
+		return nil, errors.New("DescribeFrontend(fail)")
+	}
+
+	for _, n := range w.frontendNames {
+		if name == n {
+			return mockFrontend(n), nil
+		}
+	}
+
+	return nil, interfaces.ErrUnknownFrontend
+}
+
+func (w *dummyWatcher) Subscribe() <-chan interfaces.FrontendEvent {
+	return w.events
+}
+
+func (w *dummyWatcher) Unsubscribe(events <-chan interfaces.FrontendEvent) {
+	w.unsubscribed = true
+}
+
+func mockFrontend(name string) *frontends.Frontend {
+	f, err := frontends.NewFrontend(name, "http://"+name, nil, name)
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return f
+}
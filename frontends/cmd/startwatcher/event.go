@@ -0,0 +1,25 @@
+package startwatcher
+
+import (
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Event contains the information related to a change in the frontends
+// configuration.
+type Event struct {
+	// Name of the frontend that changed.
+	Name string
+
+	// Kind specifies what happened to the frontend. Watchers that do not report
+	// the kind of change result in interfaces.EventChanged.
+	Kind interfaces.EventKind
+
+	// Revision is the revision reported by the watcher, or zero when the
+	// watcher does not track revisions.
+	Revision uint64
+
+	// Frontend holds the current configuration of the frontend. It is nil when
+	// the frontend has been deleted.
+	Frontend *frontends.Frontend
+}
@@ -0,0 +1,22 @@
+package startwatcher
+
+import (
+	"context"
+	"sync"
+)
+
+// Model provides the input for the Start Frontends Watcher Command Execute
+// method.
+type Model struct {
+	// Ctx is used to provide a means of stopping the created watcher once the
+	// command is executed. This is achieved by closing the Done channel.
+	Ctx context.Context
+
+	// WaitGroup allows the Start Frontends Watcher command to signal to the
+	// calling process that is finished cleaning up.
+	WaitGroup *sync.WaitGroup
+
+	// Events is the channel through which frontend changes are pushed back to
+	// the application.
+	Events chan<- Event
+}
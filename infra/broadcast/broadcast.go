@@ -0,0 +1,6 @@
+// Package broadcast provides helpers for repositories implementing
+// interfaces.ServiceWatcher and interfaces.FrontendWatcher.
+package broadcast
+
+// bufferSize is the number of events buffered per subscriber.
+const bufferSize = 64
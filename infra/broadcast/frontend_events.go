@@ -0,0 +1,87 @@
+package broadcast
+
+import (
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// FrontendEvents distributes frontend events to all of its subscribers. The
+// zero value is ready to use.
+type FrontendEvents struct {
+	mu          sync.Mutex
+	subscribers []chan interfaces.FrontendEvent
+	revisions   map[string]uint64
+}
+
+// Subscribe returns a new channel through which all events published after
+// subscribing are distributed. Subscribers must keep receiving from the
+// channel until they unsubscribe, as publishing blocks once its buffer is
+// full.
+func (b *FrontendEvents) Subscribe() <-chan interfaces.FrontendEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make(chan interfaces.FrontendEvent, bufferSize)
+	b.subscribers = append(b.subscribers, events)
+
+	return events
+}
+
+// Publish sends the event to all subscribers. If the event has no revision,
+// it is assigned the next revision for the frontend. Repositories that publish
+// outside of their lock should assign the revisions themselves while holding
+// it, so that revisions follow the order of the changes rather than the
+// order of publishing.
+func (b *FrontendEvents) Publish(event interfaces.FrontendEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.revisions == nil {
+		b.revisions = make(map[string]uint64)
+	}
+
+	if event.Revision == 0 {
+		event.Revision = b.revisions[event.Name] + 1
+	}
+
+	if event.Revision > b.revisions[event.Name] {
+		b.revisions[event.Name] = event.Revision
+	}
+
+	for _, subscriber := range b.subscribers {
+		subscriber <- event
+	}
+}
+
+// Unsubscribe removes the subscriber and closes its channel. Events published
+// while waiting for a blocked publish to complete are discarded.
+func (b *FrontendEvents) Unsubscribe(events <-chan interfaces.FrontendEvent) {
+	// keep receiving until the lock is acquired, as a publish holding it may
+	// be blocked on this subscriber
+	stop := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-events:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	close(stop)
+
+	for i, subscriber := range b.subscribers {
+		if subscriber == events {
+			b.subscribers = append(b.subscribers[:i], b.subscribers[i+1:]...)
+			close(subscriber)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package broadcast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestServiceEventsShouldPublishToAllSubscribers(t *testing.T) {
+	b := &ServiceEvents{}
+
+	s1 := b.Subscribe()
+	s2 := b.Subscribe()
+
+	b.Publish(interfaces.ServiceEvent{Name: "testapp", Kind: interfaces.EventCreated})
+
+	e1 := <-s1
+	e2 := <-s2
+
+	assert.Equal(t, "testapp", e1.Name)
+	assert.Equal(t, interfaces.EventCreated, e1.Kind)
+	assert.Equal(t, e1, e2)
+}
+
+func TestServiceEventsShouldIncreaseRevisionsPerService(t *testing.T) {
+	b := &ServiceEvents{}
+
+	s := b.Subscribe()
+
+	b.Publish(interfaces.ServiceEvent{Name: "a"})
+	b.Publish(interfaces.ServiceEvent{Name: "a"})
+	b.Publish(interfaces.ServiceEvent{Name: "b"})
+
+	assert.Equal(t, uint64(1), (<-s).Revision)
+	assert.Equal(t, uint64(2), (<-s).Revision)
+	assert.Equal(t, uint64(1), (<-s).Revision)
+}
+
+func TestServiceEventsShouldKeepAssignedRevisions(t *testing.T) {
+	b := &ServiceEvents{}
+
+	s := b.Subscribe()
+
+	b.Publish(interfaces.ServiceEvent{Name: "a", Revision: 2})
+	b.Publish(interfaces.ServiceEvent{Name: "a", Revision: 1})
+	b.Publish(interfaces.ServiceEvent{Name: "a"})
+
+	assert.Equal(t, uint64(2), (<-s).Revision)
+	assert.Equal(t, uint64(1), (<-s).Revision)
+	assert.Equal(t, uint64(3), (<-s).Revision)
+}
+
+func TestServiceEventsUnsubscribeShouldReleaseBlockedPublish(t *testing.T) {
+	b := &ServiceEvents{}
+
+	abandoned := b.Subscribe()
+	s := b.Subscribe()
+
+	published := make(chan struct{})
+
+	go func() {
+		defer close(published)
+
+		// fills the buffers, after which publishing blocks on abandoned
+		for i := 0; i <= bufferSize; i++ {
+			b.Publish(interfaces.ServiceEvent{Name: "a"})
+
+			if i < bufferSize {
+				<-s
+			}
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	b.Unsubscribe(abandoned)
+	<-published
+
+	_, ok := <-abandoned
+	for ok {
+		_, ok = <-abandoned
+	}
+
+	b.Publish(interfaces.ServiceEvent{Name: "a"})
+
+	assert.Len(t, s, 2)
+}
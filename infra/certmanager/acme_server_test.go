@@ -0,0 +1,352 @@
+package certmanager
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acmeServer is a minimal Pebble-style ACME server. It does not verify
+// signatures, and validates HTTP-01 challenges synchronously by sending the
+// challenge request to web instead of dialing the domain.
+type acmeServer struct {
+	*httptest.Server
+
+	web      http.Handler
+	validity time.Duration
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	mu         sync.Mutex
+	nonce      int
+	thumbprint string
+	orders     []*acmeOrder
+	authzs     []*acmeAuthz
+	issued     int
+}
+
+type acmeOrder struct {
+	domain string
+	authz  *acmeAuthz
+	cert   []byte
+}
+
+type acmeAuthz struct {
+	domain string
+	token  string
+	status string
+}
+
+type jws struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+}
+
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newACMEServer(web http.Handler, validity time.Duration) *acmeServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	if err != nil {
+		panic(err)
+	}
+
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &acmeServer{
+		web:      web,
+		validity: validity,
+		caKey:    caKey,
+		caCert:   caCert,
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+func (s *acmeServer) directoryURL() string {
+	return s.URL + "/dir"
+}
+
+// issuedCount returns the number of certificates issued.
+func (s *acmeServer) issuedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.issued
+}
+
+func (s *acmeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+
+	if r.URL.Path == "/dir" {
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+
+		return
+	}
+
+	if r.URL.Path == "/nonce" {
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	header, payload, err := parseJWS(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var id int
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 2 {
+		fmt.Sscanf(parts[1], "%d", &id)
+	}
+
+	switch parts[0] {
+	case "account":
+		s.thumbprint = thumbprint(header.JWK)
+
+		w.Header().Set("Location", s.URL+"/acct/1")
+		s.writeJSON(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case "order":
+		if len(parts) == 2 {
+			s.writeOrder(w, http.StatusOK, id)
+
+			return
+		}
+
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+
+		json.Unmarshal(payload, &req)
+
+		authz := &acmeAuthz{
+			domain: req.Identifiers[0].Value,
+			token:  fmt.Sprintf("token-%d", len(s.authzs)),
+			status: "pending",
+		}
+
+		s.authzs = append(s.authzs, authz)
+		s.orders = append(s.orders, &acmeOrder{domain: authz.domain, authz: authz})
+
+		s.writeOrder(w, http.StatusCreated, len(s.orders)-1)
+	case "authz":
+		s.writeAuthz(w, id)
+	case "chal":
+		s.validate(s.authzs[id])
+		s.writeJSON(w, http.StatusOK, s.challenge(id))
+	case "finalize":
+		var req struct{ CSR string }
+
+		json.Unmarshal(payload, &req)
+
+		err := s.issue(s.orders[id], req.CSR)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		s.writeOrder(w, http.StatusOK, id)
+	case "cert":
+		w.WriteHeader(http.StatusOK)
+		w.Write(s.orders[id].cert)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// validate requests the key authorization of the challenge from web.
+func (s *acmeServer) validate(authz *acmeAuthz) {
+	r := httptest.NewRequest(http.MethodGet,
+		"http://"+authz.domain+"/.well-known/acme-challenge/"+authz.token, nil)
+	w := httptest.NewRecorder()
+
+	s.web.ServeHTTP(w, r)
+
+	if w.Code == http.StatusOK && w.Body.String() == authz.token+"."+s.thumbprint {
+		authz.status = "valid"
+	} else {
+		authz.status = "invalid"
+	}
+}
+
+func (s *acmeServer) issue(order *acmeOrder, rawCSR string) error {
+	der, err := base64.RawURLEncoding.DecodeString(rawCSR)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+
+	if len(csr.DNSNames) != 1 || csr.DNSNames[0] != order.domain {
+		return fmt.Errorf("unexpected DNS names: %v", csr.DNSNames)
+	}
+
+	s.issued++
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(s.issued + 1)),
+		Subject:      pkix.Name{CommonName: order.domain},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(s.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	leaf, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		return err
+	}
+
+	order.cert = append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...)
+
+	return nil
+}
+
+func (s *acmeServer) writeOrder(w http.ResponseWriter, status, id int) {
+	order := s.orders[id]
+
+	orderStatus := order.authz.status
+	switch {
+	case order.cert != nil:
+		orderStatus = "valid"
+	case orderStatus == "valid":
+		orderStatus = "ready"
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/order/%d", s.URL, id))
+	s.writeJSON(w, status, map[string]interface{}{
+		"status":         orderStatus,
+		"identifiers":    []map[string]string{{"type": "dns", "value": order.domain}},
+		"authorizations": []string{fmt.Sprintf("%s/authz/%d", s.URL, id)},
+		"finalize":       fmt.Sprintf("%s/finalize/%d", s.URL, id),
+		"certificate":    fmt.Sprintf("%s/cert/%d", s.URL, id),
+	})
+}
+
+func (s *acmeServer) writeAuthz(w http.ResponseWriter, id int) {
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     s.authzs[id].status,
+		"identifier": map[string]string{"type": "dns", "value": s.authzs[id].domain},
+		"challenges": []interface{}{s.challenge(id)},
+	})
+}
+
+func (s *acmeServer) challenge(id int) map[string]string {
+	return map[string]string{
+		"type":   "http-01",
+		"url":    fmt.Sprintf("%s/chal/%d", s.URL, id),
+		"token":  s.authzs[id].token,
+		"status": s.authzs[id].status,
+	}
+}
+
+func (s *acmeServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+type jwsHeader struct {
+	JWK *jwk `json:"jwk"`
+}
+
+// parseJWS returns the protected header and payload of a JWS request body,
+// without verifying its signature.
+func parseJWS(r *http.Request) (*jwsHeader, []byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var req jws
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, nil, err
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(req.Protected)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := &jwsHeader{}
+	if err := json.Unmarshal(rawHeader, header); err != nil {
+		return nil, nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(req.Payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return header, payload, nil
+}
+
+// thumbprint returns the RFC 7638 thumbprint of an EC key.
+func thumbprint(key *jwk) string {
+	if key == nil {
+		return ""
+	}
+
+	b := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, key.Crv, key.Kty, key.X, key.Y)
+	sum := sha256.Sum256([]byte(b))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,574 @@
+// Package certmanager provides an ACME based implementation of the
+// interfaces.CertificateManager, obtaining certificates using the HTTP-01
+// challenge.
+package certmanager
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Manager defaults, used for zero values in a Config.
+const (
+	DefaultRenewBefore      = 30 * 24 * time.Hour
+	DefaultRetryInterval    = time.Minute
+	DefaultMaxRetryInterval = time.Hour
+)
+
+// Errors
+var (
+	ErrMissingDirectoryURL     = errors.New("missing directory URL")
+	ErrMissingSecureWebServer  = errors.New("missing secure web server")
+	ErrInvalidDomainName       = errors.New("invalid domain name, must be a host name without wildcard")
+	ErrInvalidRenewBefore      = errors.New("invalid renew before duration, must be greater than or equal to 0")
+	ErrInvalidRetryInterval    = errors.New("invalid retry interval, must be greater than or equal to 0")
+	ErrInvalidMaxRetryInterval = errors.New("invalid max retry interval, must be greater than or equal to 0")
+	ErrMissingHTTP01Challenge  = errors.New("authorization has no HTTP-01 challenge")
+	ErrManagerClosed           = errors.New("certificate manager closed")
+)
+
+// Config configures a Manager.
+type Config struct {
+	// DirectoryURL is the URL of the directory of the ACME server.
+	DirectoryURL string
+
+	// Email is used as the contact of the ACME account, if not empty.
+	Email string
+
+	// AccountKey is the key of the ACME account. A new ECDSA P-256 key is
+	// generated if it is nil.
+	AccountKey crypto.Signer
+
+	// HTTPClient is used for requests to the ACME server. The default client
+	// is used if it is nil.
+	HTTPClient *http.Client
+
+	// RenewBefore specifies how long before expiry a certificate is renewed.
+	RenewBefore time.Duration
+
+	// RetryInterval specifies how long to wait before retrying after a
+	// failure to obtain a certificate. It is doubled after each consecutive
+	// failure, up to MaxRetryInterval. It is also the minimum time between
+	// renewals.
+	RetryInterval time.Duration
+
+	// MaxRetryInterval caps the retry interval. It is raised to the
+	// RetryInterval if it is less.
+	MaxRetryInterval time.Duration
+
+	// Store is used to persist obtained certificates. Stored certificates
+	// are installed instead of obtaining new ones until they are due for
+	// renewal. It is optional.
+	Store interfaces.CertificateStore
+}
+
+// Manager obtains certificates from an ACME server for the domain names it
+// manages, and renews them before they expire. Certificates are installed on
+// a secure web server.
+type Manager struct {
+	logger           interfaces.Logger
+	secureWebServer  interfaces.SecureWebServer
+	store            interfaces.CertificateStore
+	client           *acme.Client
+	email            string
+	renewBefore      time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	registerMu sync.Mutex
+	registered bool
+
+	// challenges holds the key authorizations per HTTP-01 challenge path
+	challengesMu sync.RWMutex
+	challenges   map[string]string
+
+	mu      sync.Mutex
+	domains map[string]*domain
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// domain is a domain name for which a certificate is managed.
+type domain struct {
+	name   string
+	refs   int
+	cancel context.CancelFunc
+}
+
+// NewManager creates a new Manager using the provided configuration.
+func NewManager(
+	config *Config,
+	secureWebServer interfaces.SecureWebServer,
+	logger interfaces.Logger) (*Manager, error) {
+	if config.DirectoryURL == "" {
+		return nil, ErrMissingDirectoryURL
+	}
+
+	if secureWebServer == nil {
+		return nil, ErrMissingSecureWebServer
+	}
+
+	if config.RenewBefore < 0 {
+		return nil, ErrInvalidRenewBefore
+	}
+
+	if config.RetryInterval < 0 {
+		return nil, ErrInvalidRetryInterval
+	}
+
+	if config.MaxRetryInterval < 0 {
+		return nil, ErrInvalidMaxRetryInterval
+	}
+
+	accountKey := config.AccountKey
+	if accountKey == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		accountKey = key
+	}
+
+	m := &Manager{
+		logger:          logger,
+		secureWebServer: secureWebServer,
+		store:           config.Store,
+		client: &acme.Client{
+			Key:          accountKey,
+			HTTPClient:   config.HTTPClient,
+			DirectoryURL: config.DirectoryURL,
+		},
+		email:            config.Email,
+		renewBefore:      config.RenewBefore,
+		retryInterval:    config.RetryInterval,
+		maxRetryInterval: config.MaxRetryInterval,
+		challenges:       make(map[string]string),
+		domains:          make(map[string]*domain),
+	}
+
+	if m.renewBefore == 0 {
+		m.renewBefore = DefaultRenewBefore
+	}
+
+	if m.retryInterval == 0 {
+		m.retryInterval = DefaultRetryInterval
+	}
+
+	if m.maxRetryInterval == 0 {
+		m.maxRetryInterval = DefaultMaxRetryInterval
+	}
+
+	if m.maxRetryInterval < m.retryInterval {
+		m.maxRetryInterval = m.retryInterval
+	}
+
+	return m, nil
+}
+
+// ChallengeHandler returns the handler serving the key authorizations of
+// pending HTTP-01 challenges. Other requests receive a 404 Not Found.
+func (m *Manager) ChallengeHandler() http.Handler {
+	return http.HandlerFunc(m.serveChallenge)
+}
+
+func (m *Manager) serveChallenge(w http.ResponseWriter, r *http.Request) {
+	m.challengesMu.RLock()
+	keyAuth, found := m.challenges[r.URL.Path]
+	m.challengesMu.RUnlock()
+
+	if !found {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
+// ManageCertificate starts obtaining and renewing a certificate for the
+// domain name, unless it is managed already.
+func (m *Manager) ManageCertificate(domainName string) error {
+	domainName = strings.ToLower(domainName)
+
+	if domainName == "" ||
+		strings.Contains(domainName, "*") ||
+		net.ParseIP(domainName) != nil {
+		return ErrInvalidDomainName
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrManagerClosed
+	}
+
+	if d, found := m.domains[domainName]; found {
+		d.refs++
+
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &domain{
+		name:   domainName,
+		refs:   1,
+		cancel: cancel,
+	}
+
+	m.domains[domainName] = d
+
+	m.wg.Add(1)
+	go m.run(ctx, d)
+
+	return nil
+}
+
+// UnmanageCertificate stops renewing the certificate for the domain name
+// once all calls to ManageCertificate are matched. The installed certificate
+// is left in place.
+func (m *Manager) UnmanageCertificate(domainName string) {
+	domainName = strings.ToLower(domainName)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, found := m.domains[domainName]
+	if !found {
+		return
+	}
+
+	d.refs--
+	if d.refs > 0 {
+		return
+	}
+
+	d.cancel()
+	delete(m.domains, domainName)
+}
+
+// Close stops managing all domain names and waits for pending requests to
+// the ACME server to return.
+func (m *Manager) Close() {
+	m.mu.Lock()
+
+	m.closed = true
+
+	for name, d := range m.domains {
+		d.cancel()
+		delete(m.domains, name)
+	}
+
+	m.mu.Unlock()
+
+	m.wg.Wait()
+}
+
+// run installs the stored certificate for the domain, if any, and obtains a
+// new certificate whenever it is due for renewal, until ctx is done.
+func (m *Manager) run(ctx context.Context, d *domain) {
+	defer m.wg.Done()
+
+	notAfter := m.restore(d.name)
+	retryInterval := m.retryInterval
+
+	for {
+		renewAt := notAfter.Add(-m.renewBefore)
+		minWait := m.retryInterval
+
+		if !time.Now().Before(renewAt) {
+			var err error
+
+			notAfter, err = m.obtain(ctx, d.name)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				m.logger.
+					WithError(err).
+					WithField("domain_name", d.name).
+					WithField("retry_interval", retryInterval).
+					Error("obtaining certificate")
+
+				// back off exponentially on consecutive failures
+				minWait = retryInterval
+				retryInterval = nextRetryInterval(retryInterval, m.maxRetryInterval)
+			} else {
+				retryInterval = m.retryInterval
+
+				m.logger.
+					WithField("domain_name", d.name).
+					WithField("not_after", notAfter).
+					Info("installed certificate")
+			}
+
+			renewAt = notAfter.Add(-m.renewBefore)
+		}
+
+		wait := renewAt.Sub(time.Now())
+		if wait < minWait {
+			wait = minWait
+		}
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextRetryInterval returns the doubled retry interval, capped at max.
+func nextRetryInterval(retryInterval, max time.Duration) time.Duration {
+	if retryInterval >= max/2 {
+		return max
+	}
+
+	return 2 * retryInterval
+}
+
+// restore installs the stored certificate for the domain name, unless it has
+// expired. It returns the expiry time of the installed certificate, or the
+// zero time if none was installed.
+func (m *Manager) restore(domainName string) time.Time {
+	if m.store == nil {
+		return time.Time{}
+	}
+
+	stored, err := m.store.DescribeCertificate(domainName)
+	if err != nil {
+		if err != interfaces.ErrUnknownCertificate {
+			m.logger.
+				WithError(err).
+				WithField("domain_name", domainName).
+				Error("describing stored certificate")
+		}
+
+		return time.Time{}
+	}
+
+	if !time.Now().Before(stored.NotAfter) {
+		return time.Time{}
+	}
+
+	err = m.secureWebServer.UpsertCertificate(domainName, stored.Certificate)
+	if err != nil {
+		m.logger.
+			WithError(err).
+			WithField("domain_name", domainName).
+			Error("restoring certificate")
+
+		return time.Time{}
+	}
+
+	m.logger.
+		WithField("domain_name", domainName).
+		WithField("not_after", stored.NotAfter).
+		Info("restored certificate")
+
+	return stored.NotAfter
+}
+
+// obtain obtains a certificate for the domain name and installs it on the
+// secure web server. It returns the expiry time of the certificate.
+func (m *Manager) obtain(ctx context.Context, domainName string) (time.Time, error) {
+	m.logger.
+		WithField("domain_name", domainName).
+		Debug("obtaining certificate")
+
+	err := m.register(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	order, err := m.client.AuthorizeOrder(ctx, acme.DomainIDs(domainName))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		err = m.authorize(ctx, authzURL)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	order, err = m.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domainName},
+		DNSNames: []string{domainName},
+	}, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	chain, _, err := m.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	cert, err := encodeCertificate(chain, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = m.secureWebServer.UpsertCertificate(domainName, cert)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if m.store != nil {
+		// the certificate is installed, so failing to store it only means
+		// it is obtained again after a restart
+		if _, err := m.store.PutCertificate(domainName, cert); err != nil {
+			m.logger.
+				WithError(err).
+				WithField("domain_name", domainName).
+				Error("storing certificate")
+		}
+	}
+
+	return leaf.NotAfter, nil
+}
+
+// register registers the ACME account, unless it was registered already.
+func (m *Manager) register(ctx context.Context) error {
+	m.registerMu.Lock()
+	defer m.registerMu.Unlock()
+
+	if m.registered {
+		return nil
+	}
+
+	account := &acme.Account{}
+	if m.email != "" {
+		account.Contact = []string{"mailto:" + m.email}
+	}
+
+	_, err := m.client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+
+	m.registered = true
+
+	return nil
+}
+
+// authorize completes the HTTP-01 challenge of the authorization, unless it
+// is valid already.
+func (m *Manager) authorize(ctx context.Context, authzURL string) error {
+	authz, err := m.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+
+	for _, c := range authz.Challenges {
+		if c.Type == "http-01" {
+			challenge = c
+
+			break
+		}
+	}
+
+	if challenge == nil {
+		return ErrMissingHTTP01Challenge
+	}
+
+	keyAuth, err := m.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return err
+	}
+
+	path := m.client.HTTP01ChallengePath(challenge.Token)
+
+	m.challengesMu.Lock()
+	m.challenges[path] = keyAuth
+	m.challengesMu.Unlock()
+
+	defer func() {
+		m.challengesMu.Lock()
+		delete(m.challenges, path)
+		m.challengesMu.Unlock()
+	}()
+
+	_, err = m.client.Accept(ctx, challenge)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.client.WaitAuthorization(ctx, authz.URI)
+
+	return err
+}
+
+// encodeCertificate PEM encodes the DER encoded certificate chain and the
+// private key.
+func encodeCertificate(chain [][]byte, key *ecdsa.PrivateKey) (*frontends.Certificate, error) {
+	var certPEM []byte
+
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: der,
+		})...)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &frontends.Certificate{
+		Certificate: certPEM,
+		PrivateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyDER,
+		}),
+	}, nil
+}
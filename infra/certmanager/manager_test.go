@@ -0,0 +1,285 @@
+package certmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/certstore"
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/infra/webserver"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+func mustParse(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return u
+}
+
+// newTestManager creates a manager against a stand-in ACME server, which
+// validates challenges using the returned web server.
+func newTestManager(t *testing.T, config *Config) (*Manager, *acmeServer, *webserver.WebServer, *webserver.SecureWebServer) {
+	web := webserver.NewWebServer()
+	secureWeb := webserver.NewSecureWebServer()
+	acmeSrv := newACMEServer(web, 90*24*time.Hour)
+
+	config.DirectoryURL = acmeSrv.directoryURL()
+
+	m, err := NewManager(config, secureWeb, logger)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return m, acmeSrv, web, secureWeb
+}
+
+// waitForCertificate waits until the secure web server has a certificate for
+// the server name.
+func waitForCertificate(t *testing.T, s *webserver.SecureWebServer, serverName string) *tls.Certificate {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		cert, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		if err == nil {
+			return cert
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no certificate for %s", serverName)
+
+	return nil
+}
+
+func TestNewManagerShouldReturnErrorOnMissingDirectoryURL(t *testing.T) {
+	m, err := NewManager(&Config{}, webserver.NewSecureWebServer(), logger)
+
+	assert.Nil(t, m)
+	assert.Equal(t, ErrMissingDirectoryURL, err)
+}
+
+func TestNewManagerShouldReturnErrorOnMissingSecureWebServer(t *testing.T) {
+	m, err := NewManager(&Config{DirectoryURL: "http://acme/dir"}, nil, logger)
+
+	assert.Nil(t, m)
+	assert.Equal(t, ErrMissingSecureWebServer, err)
+}
+
+func TestNewManagerShouldReturnErrorOnNegativeDurations(t *testing.T) {
+	_, err := NewManager(&Config{
+		DirectoryURL: "http://acme/dir",
+		RenewBefore:  -1,
+	}, webserver.NewSecureWebServer(), logger)
+
+	assert.Equal(t, ErrInvalidRenewBefore, err)
+
+	_, err = NewManager(&Config{
+		DirectoryURL:  "http://acme/dir",
+		RetryInterval: -1,
+	}, webserver.NewSecureWebServer(), logger)
+
+	assert.Equal(t, ErrInvalidRetryInterval, err)
+
+	_, err = NewManager(&Config{
+		DirectoryURL:     "http://acme/dir",
+		MaxRetryInterval: -1,
+	}, webserver.NewSecureWebServer(), logger)
+
+	assert.Equal(t, ErrInvalidMaxRetryInterval, err)
+}
+
+func TestNextRetryIntervalShouldDoubleUpToMax(t *testing.T) {
+	assert.Equal(t, 2*time.Minute, nextRetryInterval(time.Minute, time.Hour))
+	assert.Equal(t, 40*time.Minute, nextRetryInterval(20*time.Minute, time.Hour))
+	assert.Equal(t, time.Hour, nextRetryInterval(40*time.Minute, time.Hour))
+	assert.Equal(t, time.Hour, nextRetryInterval(time.Hour, time.Hour))
+}
+
+func TestManageCertificateShouldRejectInvalidDomainNames(t *testing.T) {
+	m, err := NewManager(&Config{DirectoryURL: "http://acme/dir"}, webserver.NewSecureWebServer(), logger)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	defer m.Close()
+
+	for _, name := range []string{"", "*.example.com", "127.0.0.1", "::1"} {
+		assert.Equal(t, ErrInvalidDomainName, m.ManageCertificate(name), name)
+	}
+}
+
+func TestManagerShouldObtainCertificate(t *testing.T) {
+	m, acmeSrv, web, secureWeb := newTestManager(t, &Config{Email: "admin@example.com"})
+	defer acmeSrv.Close()
+	defer m.Close()
+
+	web.UpsertRoute(mustParse("http://example.com/.well-known/acme-challenge"), m.ChallengeHandler())
+
+	assert.Nil(t, m.ManageCertificate("example.com"))
+
+	cert := waitForCertificate(t, secureWeb, "example.com")
+
+	assert.Len(t, cert.Certificate, 2)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"example.com"}, leaf.DNSNames)
+	}
+
+	// challenges are removed once the authorization is completed
+	w := httptest.NewRecorder()
+	web.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://example.com/.well-known/acme-challenge/token-0", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestManagerShouldRenewCertificate(t *testing.T) {
+	// renew continuously, limited by the retry interval
+	m, acmeSrv, web, _ := newTestManager(t, &Config{
+		RenewBefore:   365 * 24 * time.Hour,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	defer acmeSrv.Close()
+	defer m.Close()
+
+	web.UpsertRoute(mustParse("http://example.com/.well-known/acme-challenge"), m.ChallengeHandler())
+
+	assert.Nil(t, m.ManageCertificate("example.com"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for acmeSrv.issuedCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.True(t, acmeSrv.issuedCount() >= 2)
+}
+
+func TestManagerShouldRetryFailedChallenges(t *testing.T) {
+	m, acmeSrv, web, secureWeb := newTestManager(t, &Config{
+		RetryInterval: 10 * time.Millisecond,
+	})
+	defer acmeSrv.Close()
+	defer m.Close()
+
+	// the challenge is not routed yet, so validation fails
+	assert.Nil(t, m.ManageCertificate("example.com"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, 0, acmeSrv.issuedCount())
+
+	web.UpsertRoute(mustParse("http://example.com/.well-known/acme-challenge"), m.ChallengeHandler())
+
+	waitForCertificate(t, secureWeb, "example.com")
+}
+
+func TestManagerShouldRestoreStoredCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certmanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	store, err := certstore.NewDir(dir, logger)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	m, acmeSrv, web, secureWeb := newTestManager(t, &Config{Store: store})
+	defer acmeSrv.Close()
+
+	web.UpsertRoute(mustParse("http://example.com/.well-known/acme-challenge"), m.ChallengeHandler())
+
+	assert.Nil(t, m.ManageCertificate("example.com"))
+
+	waitForCertificate(t, secureWeb, "example.com")
+
+	// closing waits for the certificate to be stored
+	m.Close()
+
+	stored, err := store.DescribeCertificate("example.com")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// after a restart the stored certificate is installed without obtaining
+	// a new one
+	store, _ = certstore.NewDir(dir, logger)
+
+	m, acmeSrv, _, secureWeb = newTestManager(t, &Config{Store: store})
+	defer acmeSrv.Close()
+	defer m.Close()
+
+	assert.Nil(t, m.ManageCertificate("example.com"))
+
+	cert := waitForCertificate(t, secureWeb, "example.com")
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if assert.Nil(t, err) {
+		assert.Equal(t, stored.NotAfter, leaf.NotAfter)
+	}
+
+	assert.Equal(t, 0, acmeSrv.issuedCount())
+}
+
+func TestUnmanageCertificateShouldCountReferences(t *testing.T) {
+	m, acmeSrv, _, _ := newTestManager(t, &Config{})
+	defer acmeSrv.Close()
+	defer m.Close()
+
+	assert.Nil(t, m.ManageCertificate("example.com"))
+	assert.Nil(t, m.ManageCertificate("EXAMPLE.com"))
+
+	m.UnmanageCertificate("example.com")
+
+	m.mu.Lock()
+	assert.Contains(t, m.domains, "example.com")
+	m.mu.Unlock()
+
+	m.UnmanageCertificate("example.com")
+
+	m.mu.Lock()
+	assert.NotContains(t, m.domains, "example.com")
+	m.mu.Unlock()
+}
+
+func TestChallengeHandlerShouldReturnNotFoundForUnknownTokens(t *testing.T) {
+	m, err := NewManager(&Config{DirectoryURL: "http://acme/dir"}, webserver.NewSecureWebServer(), logger)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	w := httptest.NewRecorder()
+	m.ChallengeHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"http://example.com/.well-known/acme-challenge/unknown", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), "not found"))
+}
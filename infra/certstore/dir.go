@@ -0,0 +1,295 @@
+// Package certstore provides a filesystem implementation of the
+// interfaces.CertificateStore.
+package certstore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Errors
+var (
+	ErrMissingPath        = errors.New("missing path")
+	ErrInvalidDomainName  = errors.New("invalid domain name")
+	ErrMissingCertificate = errors.New("missing certificate")
+)
+
+// certificateExt is the extension of the files holding the certificates.
+const certificateExt = ".pem"
+
+// Dir stores certificates in a directory, one file per domain name holding
+// the PEM encoded certificate chain followed by the private key:
+//
+//	api.example.com.pem
+//	_.example.com.pem     certificate for *.example.com
+//
+// Files are only readable by the owner, and are replaced atomically. All
+// certificates are loaded when the Dir is created.
+type Dir struct {
+	path   string
+	logger interfaces.Logger
+
+	mu    sync.RWMutex
+	certs map[string]*interfaces.StoredCertificate
+}
+
+// NewDir creates a new Dir, creating the directory at path if it does not
+// exist, and loads the certificates it holds. Files that cannot be loaded are
+// logged and skipped.
+func NewDir(path string, logger interfaces.Logger) (*Dir, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	d := &Dir{
+		path:   path,
+		logger: logger,
+		certs:  make(map[string]*interfaces.StoredCertificate),
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// ListCertificates returns all certificates in the directory, ordered by
+// domain name.
+func (d *Dir) ListCertificates() ([]*interfaces.StoredCertificate, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	certs := make([]*interfaces.StoredCertificate, 0, len(d.certs))
+	for _, cert := range d.certs {
+		certs = append(certs, cert)
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].DomainName < certs[j].DomainName
+	})
+
+	return certs, nil
+}
+
+// DescribeCertificate returns the certificate for the domain name. The port
+// of the domain name, if any, is ignored.
+func (d *Dir) DescribeCertificate(domainName string) (*interfaces.StoredCertificate, error) {
+	name, err := normalize(domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	cert, found := d.certs[name]
+	if !found {
+		return nil, interfaces.ErrUnknownCertificate
+	}
+
+	return cert, nil
+}
+
+// PutCertificate writes the certificate for the domain name, unless the same
+// certificate is stored already. The certificate must match its private key.
+func (d *Dir) PutCertificate(domainName string, cert *frontends.Certificate) (*interfaces.StoredCertificate, error) {
+	name, err := normalize(domainName)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert == nil {
+		return nil, ErrMissingCertificate
+	}
+
+	stored, err := newStoredCertificate(name, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if current, found := d.certs[name]; found &&
+		bytes.Equal(current.Certificate.Certificate, cert.Certificate) &&
+		bytes.Equal(current.Certificate.PrivateKey, cert.PrivateKey) {
+		return current, nil
+	}
+
+	err = d.write(name, cert)
+	if err != nil {
+		return nil, err
+	}
+
+	d.certs[name] = stored
+
+	return stored, nil
+}
+
+// write replaces the file for the domain name by writing a temporary file
+// and renaming it.
+func (d *Dir) write(name string, cert *frontends.Certificate) error {
+	data := append([]byte{}, cert.Certificate...)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data, '\n')
+	}
+
+	data = append(data, cert.PrivateKey...)
+
+	// temporary files are created with mode 0600
+	f, err := ioutil.TempFile(d.path, ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(d.path, fileName(name)))
+	}
+
+	if err != nil {
+		os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// load reads all certificate files in the directory.
+func (d *Dir) load() error {
+	infos, err := ioutil.ReadDir(d.path)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), certificateExt) {
+			continue
+		}
+
+		path := filepath.Join(d.path, info.Name())
+
+		cert, err := loadFile(path)
+		if err != nil {
+			d.logger.
+				WithError(err).
+				WithField("path", path).
+				Error("loading certificate")
+
+			continue
+		}
+
+		d.certs[cert.DomainName] = cert
+	}
+
+	return nil
+}
+
+func loadFile(path string) (*interfaces.StoredCertificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &frontends.Certificate{}
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, pem.EncodeToMemory(block)...)
+		} else {
+			cert.PrivateKey = append(cert.PrivateKey, pem.EncodeToMemory(block)...)
+		}
+	}
+
+	name, err := normalize(domainName(filepath.Base(path)))
+	if err != nil {
+		return nil, err
+	}
+
+	return newStoredCertificate(name, cert)
+}
+
+// newStoredCertificate verifies that the certificate matches its private key
+// and returns it together with the validity period of its leaf certificate.
+func newStoredCertificate(name string, cert *frontends.Certificate) (*interfaces.StoredCertificate, error) {
+	tlsCert, err := tls.X509KeyPair(cert.Certificate, cert.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &interfaces.StoredCertificate{
+		DomainName:  name,
+		Certificate: cert,
+		NotBefore:   leaf.NotBefore,
+		NotAfter:    leaf.NotAfter,
+	}, nil
+}
+
+// normalize returns the lower case domain name without port. Domain names
+// that cannot be used as a file name are rejected.
+func normalize(domainName string) (string, error) {
+	u := url.URL{Host: domainName}
+	name := strings.ToLower(u.Hostname())
+
+	if name == "" ||
+		strings.HasPrefix(name, ".") ||
+		strings.ContainsAny(name, `/\`) {
+		return "", ErrInvalidDomainName
+	}
+
+	return name, nil
+}
+
+// fileName returns the name of the file for a domain name, replacing a
+// leading wildcard with an underscore.
+func fileName(domainName string) string {
+	if strings.HasPrefix(domainName, "*.") {
+		domainName = "_" + domainName[1:]
+	}
+
+	return domainName + certificateExt
+}
+
+// domainName returns the domain name for a file name.
+func domainName(fileName string) string {
+	name := strings.TrimSuffix(fileName, certificateExt)
+
+	if strings.HasPrefix(name, "_.") {
+		name = "*" + name[1:]
+	}
+
+	return name
+}
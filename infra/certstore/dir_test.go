@@ -0,0 +1,204 @@
+package certstore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "certstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// selfSigned returns a PEM encoded self-signed certificate for the domain
+// name, which expires at notAfter.
+func selfSigned(domainName string, notAfter time.Time) *frontends.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domainName},
+		DNSNames:     []string{domainName},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return &frontends.Certificate{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestNewDirShouldReturnErrorOnMissingPath(t *testing.T) {
+	d, err := NewDir("", logger)
+
+	assert.Nil(t, d)
+	assert.Equal(t, ErrMissingPath, err)
+}
+
+func TestNewDirShouldCreateDirectory(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "certs")
+
+	_, err := NewDir(path, logger)
+	assert.Nil(t, err)
+
+	info, err := os.Stat(path)
+	if assert.Nil(t, err) {
+		assert.True(t, info.IsDir())
+	}
+}
+
+func TestPutCertificate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+	cert := selfSigned("api.example.com", notAfter)
+
+	stored, err := d.PutCertificate("API.example.com:443", cert)
+	assert.Nil(t, err)
+	assert.Equal(t, "api.example.com", stored.DomainName)
+	assert.Equal(t, notAfter, stored.NotAfter)
+
+	described, err := d.DescribeCertificate("api.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, stored, described)
+
+	info, err := os.Stat(filepath.Join(dir, "api.example.com.pem"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestPutCertificateShouldRejectMismatchedKey(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	cert := selfSigned("api.example.com", time.Now().Add(time.Hour))
+	cert.PrivateKey = selfSigned("api.example.com", time.Now().Add(time.Hour)).PrivateKey
+
+	_, err := d.PutCertificate("api.example.com", cert)
+	assert.Error(t, err)
+
+	_, err = d.DescribeCertificate("api.example.com")
+	assert.Equal(t, interfaces.ErrUnknownCertificate, err)
+}
+
+func TestPutCertificateShouldRejectInvalidDomainNames(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	cert := selfSigned("api.example.com", time.Now().Add(time.Hour))
+
+	for _, name := range []string{"", "../etc", "a/b"} {
+		_, err := d.PutCertificate(name, cert)
+		assert.Equal(t, ErrInvalidDomainName, err, name)
+	}
+
+	_, err := d.PutCertificate("api.example.com", nil)
+	assert.Equal(t, ErrMissingCertificate, err)
+}
+
+func TestNewDirShouldLoadStoredCertificates(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	d.PutCertificate("api.example.com", selfSigned("api.example.com", time.Now().Add(time.Hour)))
+	d.PutCertificate("*.example.com", selfSigned("*.example.com", time.Now().Add(time.Hour)))
+
+	// files that cannot be loaded are skipped
+	ioutil.WriteFile(filepath.Join(dir, "broken.example.com.pem"), []byte("broken"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0600)
+
+	_, err := os.Stat(filepath.Join(dir, "_.example.com.pem"))
+	assert.Nil(t, err)
+
+	reloaded, err := NewDir(dir, logger)
+	assert.Nil(t, err)
+
+	expected, _ := d.ListCertificates()
+	certs, err := reloaded.ListCertificates()
+	assert.Nil(t, err)
+
+	if assert.Len(t, certs, 2) {
+		assert.Equal(t, "*.example.com", certs[0].DomainName)
+		assert.Equal(t, "api.example.com", certs[1].DomainName)
+		assert.Equal(t, expected[0].NotAfter, certs[0].NotAfter)
+		assert.Equal(t, expected[1].Certificate, certs[1].Certificate)
+	}
+}
+
+func TestPutCertificateShouldSkipUnchangedCertificates(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	cert := selfSigned("api.example.com", time.Now().Add(time.Hour))
+
+	first, _ := d.PutCertificate("api.example.com", cert)
+
+	path := filepath.Join(dir, "api.example.com.pem")
+	os.Remove(path)
+
+	second, err := d.PutCertificate("api.example.com", cert)
+	assert.Nil(t, err)
+	assert.True(t, first == second)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,331 @@
+package filerepo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// config models the contents of a configuration file.
+type config struct {
+	Services  map[string]*serviceConfig  `yaml:"services" json:"services"`
+	Frontends map[string]*frontendConfig `yaml:"frontends" json:"frontends"`
+}
+
+type serviceConfig struct {
+	Servers          []string                `yaml:"servers" json:"servers"`
+	Strategy         string                  `yaml:"strategy" json:"strategy"`
+	HashPolicy       *hashPolicyConfig       `yaml:"hashPolicy" json:"hashPolicy"`
+	StickySessions   *stickySessionsConfig   `yaml:"stickySessions" json:"stickySessions"`
+	HealthCheck      *healthCheckConfig      `yaml:"healthCheck" json:"healthCheck"`
+	OutlierDetection *outlierDetectionConfig `yaml:"outlierDetection" json:"outlierDetection"`
+}
+
+type hashPolicyConfig struct {
+	Header string `yaml:"header" json:"header"`
+	Cookie string `yaml:"cookie" json:"cookie"`
+}
+
+type stickySessionsConfig struct {
+	CookieName string `yaml:"cookieName" json:"cookieName"`
+	TTL        string `yaml:"ttl" json:"ttl"`
+}
+
+// healthCheckConfig holds durations as strings, e.g. 10s, so they can be
+// written the same way in YAML and JSON.
+type healthCheckConfig struct {
+	Path               string `yaml:"path" json:"path"`
+	Interval           string `yaml:"interval" json:"interval"`
+	Timeout            string `yaml:"timeout" json:"timeout"`
+	HealthyThreshold   int    `yaml:"healthyThreshold" json:"healthyThreshold"`
+	UnhealthyThreshold int    `yaml:"unhealthyThreshold" json:"unhealthyThreshold"`
+}
+
+type frontendConfig struct {
+	URL                   string                 `yaml:"url" json:"url"`
+	Service               string                 `yaml:"service" json:"service"`
+	Certificate           *certificateConfig     `yaml:"certificate" json:"certificate"`
+	AutoTLS               bool                   `yaml:"autoTLS" json:"autoTLS"`
+	RedirectStatus        int                    `yaml:"redirectStatus" json:"redirectStatus"`
+	SkipChallengeRedirect bool                   `yaml:"skipChallengeRedirect" json:"skipChallengeRedirect"`
+	SecurityHeaders       *securityHeadersConfig `yaml:"securityHeaders" json:"securityHeaders"`
+	Middlewares           []*middlewareConfig    `yaml:"middlewares" json:"middlewares"`
+	PathRewrite           *pathRewriteConfig     `yaml:"pathRewrite" json:"pathRewrite"`
+}
+
+type pathRewriteConfig struct {
+	StripPrefix string `yaml:"stripPrefix" json:"stripPrefix"`
+	Regex       string `yaml:"regex" json:"regex"`
+	Replacement string `yaml:"replacement" json:"replacement"`
+	AddPrefix   string `yaml:"addPrefix" json:"addPrefix"`
+}
+
+type middlewareConfig struct {
+	Name    string            `yaml:"name" json:"name"`
+	Options map[string]string `yaml:"options" json:"options"`
+}
+
+type securityHeadersConfig struct {
+	HSTS                  *hstsConfig       `yaml:"hsts" json:"hsts"`
+	ContentTypeNosniff    bool              `yaml:"contentTypeNosniff" json:"contentTypeNosniff"`
+	FrameOptions          string            `yaml:"frameOptions" json:"frameOptions"`
+	ContentSecurityPolicy string            `yaml:"contentSecurityPolicy" json:"contentSecurityPolicy"`
+	ReferrerPolicy        string            `yaml:"referrerPolicy" json:"referrerPolicy"`
+	Headers               map[string]string `yaml:"headers" json:"headers"`
+}
+
+type hstsConfig struct {
+	MaxAge            string `yaml:"maxAge" json:"maxAge"`
+	IncludeSubDomains bool   `yaml:"includeSubDomains" json:"includeSubDomains"`
+	Preload           bool   `yaml:"preload" json:"preload"`
+}
+
+// certificateConfig holds the paths to PEM encoded files. Relative paths are
+// resolved against the directory of the configuration file.
+type certificateConfig struct {
+	Certificate string `yaml:"certificate" json:"certificate"`
+	PrivateKey  string `yaml:"privateKey" json:"privateKey"`
+}
+
+// unmarshal parses data into v as JSON if path has a .json extension, and as
+// YAML otherwise.
+func unmarshal(path string, data []byte, v interface{}) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return json.Unmarshal(data, v)
+	}
+
+	return yaml.Unmarshal(data, v)
+}
+
+func (c *serviceConfig) service(name string) (*services.Service, error) {
+	return services.NewService(name, c.Servers...)
+}
+
+type outlierDetectionConfig struct {
+	ConsecutiveErrors int    `yaml:"consecutiveErrors" json:"consecutiveErrors"`
+	BaseEjectionTime  string `yaml:"baseEjectionTime" json:"baseEjectionTime"`
+	MaxEjectionTime   string `yaml:"maxEjectionTime" json:"maxEjectionTime"`
+}
+
+// options returns the service options, or nil if none are configured.
+func (c *serviceConfig) options() (*interfaces.ServiceOptions, error) {
+	if c.Strategy == "" &&
+		c.HashPolicy == nil &&
+		c.StickySessions == nil &&
+		c.HealthCheck == nil &&
+		c.OutlierDetection == nil {
+		return nil, nil
+	}
+
+	options := &interfaces.ServiceOptions{
+		Strategy: interfaces.Strategy(c.Strategy),
+	}
+
+	if c.HashPolicy != nil {
+		options.HashPolicy = &interfaces.HashPolicy{
+			Header: c.HashPolicy.Header,
+			Cookie: c.HashPolicy.Cookie,
+		}
+	}
+
+	if c.StickySessions != nil {
+		ttl, err := parseDuration(c.StickySessions.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("sticky sessions ttl: %v", err)
+		}
+
+		options.StickySessions = &interfaces.StickySessions{
+			CookieName: c.StickySessions.CookieName,
+			TTL:        ttl,
+		}
+	}
+
+	if c.HealthCheck != nil {
+		healthCheck, err := c.HealthCheck.healthCheck()
+		if err != nil {
+			return nil, err
+		}
+
+		options.HealthCheck = healthCheck
+	}
+
+	if c.OutlierDetection != nil {
+		outlierDetection, err := c.OutlierDetection.outlierDetection()
+		if err != nil {
+			return nil, err
+		}
+
+		options.OutlierDetection = outlierDetection
+	}
+
+	return options, nil
+}
+
+func (c *healthCheckConfig) healthCheck() (*interfaces.HealthCheck, error) {
+	interval, err := parseDuration(c.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("health check interval: %v", err)
+	}
+
+	timeout, err := parseDuration(c.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("health check timeout: %v", err)
+	}
+
+	return &interfaces.HealthCheck{
+		Path:               c.Path,
+		Interval:           interval,
+		Timeout:            timeout,
+		HealthyThreshold:   c.HealthyThreshold,
+		UnhealthyThreshold: c.UnhealthyThreshold,
+	}, nil
+}
+
+func (c *outlierDetectionConfig) outlierDetection() (*interfaces.OutlierDetection, error) {
+	baseEjectionTime, err := parseDuration(c.BaseEjectionTime)
+	if err != nil {
+		return nil, fmt.Errorf("outlier detection base ejection time: %v", err)
+	}
+
+	maxEjectionTime, err := parseDuration(c.MaxEjectionTime)
+	if err != nil {
+		return nil, fmt.Errorf("outlier detection max ejection time: %v", err)
+	}
+
+	return &interfaces.OutlierDetection{
+		ConsecutiveErrors: c.ConsecutiveErrors,
+		BaseEjectionTime:  baseEjectionTime,
+		MaxEjectionTime:   maxEjectionTime,
+	}, nil
+}
+
+// parseDuration parses a duration, returning 0 for an empty string.
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+func (c *frontendConfig) frontend(name, dir string) (*frontends.Frontend, error) {
+	var cert *frontends.Certificate
+
+	if c.Certificate != nil {
+		var err error
+
+		cert, err = c.Certificate.load(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return frontends.NewFrontend(name, c.URL, cert, c.Service)
+}
+
+// options returns the frontend options, or nil if none are configured.
+func (c *frontendConfig) options() (*interfaces.FrontendOptions, error) {
+	if !c.AutoTLS &&
+		c.RedirectStatus == 0 &&
+		!c.SkipChallengeRedirect &&
+		c.SecurityHeaders == nil &&
+		len(c.Middlewares) < 1 &&
+		c.PathRewrite == nil {
+		return nil, nil
+	}
+
+	options := &interfaces.FrontendOptions{
+		AutoTLS:               c.AutoTLS,
+		RedirectStatus:        c.RedirectStatus,
+		SkipChallengeRedirect: c.SkipChallengeRedirect,
+	}
+
+	if c.PathRewrite != nil {
+		options.PathRewrite = &interfaces.PathRewrite{
+			StripPrefix: c.PathRewrite.StripPrefix,
+			Regex:       c.PathRewrite.Regex,
+			Replacement: c.PathRewrite.Replacement,
+			AddPrefix:   c.PathRewrite.AddPrefix,
+		}
+	}
+
+	for _, m := range c.Middlewares {
+		if m == nil || m.Name == "" {
+			return nil, ErrMissingMiddlewareName
+		}
+
+		options.Middlewares = append(options.Middlewares, &interfaces.MiddlewareConfig{
+			Name:    m.Name,
+			Options: m.Options,
+		})
+	}
+
+	if c.SecurityHeaders != nil {
+		options.SecurityHeaders = &interfaces.SecurityHeaders{
+			ContentTypeNosniff:    c.SecurityHeaders.ContentTypeNosniff,
+			FrameOptions:          c.SecurityHeaders.FrameOptions,
+			ContentSecurityPolicy: c.SecurityHeaders.ContentSecurityPolicy,
+			ReferrerPolicy:        c.SecurityHeaders.ReferrerPolicy,
+			Headers:               c.SecurityHeaders.Headers,
+		}
+
+		if c.SecurityHeaders.HSTS != nil {
+			maxAge, err := parseDuration(c.SecurityHeaders.HSTS.MaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("hsts max age: %v", err)
+			}
+
+			options.SecurityHeaders.HSTS = &interfaces.HSTS{
+				MaxAge:            maxAge,
+				IncludeSubDomains: c.SecurityHeaders.HSTS.IncludeSubDomains,
+				Preload:           c.SecurityHeaders.HSTS.Preload,
+			}
+		}
+	}
+
+	return options, nil
+}
+
+func (c *certificateConfig) load(dir string) (*frontends.Certificate, error) {
+	paths := c.paths(dir)
+
+	certPEM, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := ioutil.ReadFile(paths[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &frontends.Certificate{
+		Certificate: certPEM,
+		PrivateKey:  keyPEM,
+	}, nil
+}
+
+// paths returns the resolved paths of the certificate and private key files.
+func (c *certificateConfig) paths(dir string) []string {
+	return []string{
+		resolvePath(dir, c.Certificate),
+		resolvePath(dir, c.PrivateKey),
+	}
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,371 @@
+package filerepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// Subdirectories of a Dir.
+const (
+	ServicesDir  = "services"
+	FrontendsDir = "frontends"
+)
+
+// Dir loads services and frontends from a directory holding one manifest
+// file per entity, named after the entity:
+//
+//	services/api.yaml     servers: [http://10.0.0.1:8080]
+//	frontends/api.yaml    url: https://api.example.com
+//	                      service: api
+//	                      certificate:
+//	                        certificate: ../certs/api.crt
+//	                        privateKey: ../certs/api.key
+//
+// Manifests have a .yaml, .yml or .json extension. Files with a .json
+// extension are parsed as JSON, all others as YAML. Relative certificate
+// paths are resolved against the directory of the manifest.
+type Dir struct {
+	*store
+
+	path   string
+	logger interfaces.Logger
+
+	// manifests caches the parsed manifests by file path, guarded by
+	// reloadMu
+	reloadMu  sync.Mutex
+	manifests map[string]*manifest
+}
+
+// manifest holds a parsed manifest file, together with the information used
+// to detect changes to it and to the files it references.
+type manifest struct {
+	modTime time.Time
+	size    int64
+	hash    []byte
+	entity  interface{}
+
+	// files are the referenced files, e.g. certificates, and filesHash is
+	// the hash of their contents when the manifest was parsed
+	files     []string
+	filesHash []byte
+}
+
+// NewDir creates a new Dir and loads the manifests in the directory at path.
+func NewDir(path string, logger interfaces.Logger) (*Dir, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	d := &Dir{
+		store:     newStore(),
+		path:      path,
+		logger:    logger,
+		manifests: make(map[string]*manifest),
+	}
+
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Reload scans the directory and publishes events for all services and
+// frontends whose manifest was added, changed or removed since the last
+// scan. Manifests are only parsed again if their modification time, size or
+// content hash changed, or if the content of a referenced certificate file
+// changed. Invalid manifests are logged, keeping the entity as
+// it was last loaded. If a subdirectory cannot be read, an error is returned
+// and the current configuration is kept.
+func (d *Dir) Reload() error {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	if _, err := os.Stat(d.path); err != nil {
+		return err
+	}
+
+	serviceEntities, err := d.load(ServicesDir, parseService)
+	if err != nil {
+		return err
+	}
+
+	frontendEntities, err := d.load(FrontendsDir, parseFrontend)
+	if err != nil {
+		return err
+	}
+
+	loadedServices := make(map[string]*services.Service, len(serviceEntities))
+	loadedServiceOptions := make(map[string]*interfaces.ServiceOptions)
+
+	for name, entity := range serviceEntities {
+		e := entity.(*serviceEntity)
+
+		loadedServices[name] = e.service
+
+		if e.options != nil {
+			loadedServiceOptions[name] = e.options
+		}
+	}
+
+	loadedFrontends := make(map[string]*frontends.Frontend, len(frontendEntities))
+	loadedFrontendOptions := make(map[string]*interfaces.FrontendOptions)
+
+	for name, entity := range frontendEntities {
+		e := entity.(*frontendEntity)
+
+		loadedFrontends[name] = e.frontend
+
+		if e.options != nil {
+			loadedFrontendOptions[name] = e.options
+		}
+	}
+
+	d.replace(loadedServices, loadedServiceOptions, loadedFrontends, loadedFrontendOptions)
+
+	return nil
+}
+
+// Watch scans the directory at the provided interval until ctx is done.
+// Reload errors are logged.
+func (d *Dir) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			if err := d.Reload(); err != nil {
+				d.logger.
+					WithError(err).
+					WithField("path", d.path).
+					Error("scanning manifest directory")
+			}
+		}
+	}
+}
+
+// parseFunc parses a manifest, returning the entity and the paths of the
+// files it references.
+type parseFunc func(name, path string, data []byte) (interface{}, []string, error)
+
+// serviceEntity is the entity parsed from a service manifest.
+type serviceEntity struct {
+	service *services.Service
+	options *interfaces.ServiceOptions
+}
+
+func parseService(name, path string, data []byte) (interface{}, []string, error) {
+	c := &serviceConfig{}
+	if err := unmarshal(path, data, c); err != nil {
+		return nil, nil, err
+	}
+
+	service, err := c.service(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options, err := c.options()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &serviceEntity{
+		service: service,
+		options: options,
+	}, nil, nil
+}
+
+// frontendEntity is the entity parsed from a frontend manifest.
+type frontendEntity struct {
+	frontend *frontends.Frontend
+	options  *interfaces.FrontendOptions
+}
+
+func parseFrontend(name, path string, data []byte) (interface{}, []string, error) {
+	c := &frontendConfig{}
+	if err := unmarshal(path, data, c); err != nil {
+		return nil, nil, err
+	}
+
+	dir := filepath.Dir(path)
+
+	frontend, err := c.frontend(name, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options, err := c.options()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	if c.Certificate != nil {
+		files = c.Certificate.paths(dir)
+	}
+
+	return &frontendEntity{
+		frontend: frontend,
+		options:  options,
+	}, files, nil
+}
+
+// load returns the entities defined by the manifests in subdir, keyed by
+// name. A missing subdir contains no entities. It returns an error if subdir
+// cannot be read, so its entities are not mistaken for removed ones.
+func (d *Dir) load(subdir string, parse parseFunc) (map[string]interface{}, error) {
+	dir := filepath.Join(d.path, subdir)
+	entities := make(map[string]interface{})
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	for _, info := range infos {
+		name, ok := manifestName(info)
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, info.Name())
+		seen[path] = true
+
+		if _, found := entities[name]; found {
+			d.logger.
+				WithField("path", path).
+				Warn("ignoring duplicate manifest")
+
+			continue
+		}
+
+		m, err := d.loadManifest(name, path, info, parse)
+		if err != nil {
+			d.logger.
+				WithError(err).
+				WithField("path", path).
+				Error("loading manifest")
+		}
+
+		if m != nil {
+			entities[name] = m.entity
+		}
+	}
+
+	// forget manifests that were removed
+	for path := range d.manifests {
+		if filepath.Dir(path) == dir && !seen[path] {
+			delete(d.manifests, path)
+		}
+	}
+
+	return entities, nil
+}
+
+// loadManifest returns the cached manifest at path if neither the file nor
+// the files it references changed, or parses it otherwise. If parsing fails
+// the cached manifest is returned together with the error.
+func (d *Dir) loadManifest(
+	name, path string,
+	info os.FileInfo,
+	parse parseFunc) (*manifest, error) {
+	cached := d.manifests[path]
+
+	// referenced files, e.g. rotated certificates, are compared by content
+	filesChanged := cached != nil && !bytes.Equal(cached.filesHash, hashFiles(cached.files))
+
+	if cached != nil && !filesChanged &&
+		cached.modTime.Equal(info.ModTime()) &&
+		cached.size == info.Size() {
+		return cached, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cached, err
+	}
+
+	hash := sha256.Sum256(data)
+
+	if cached != nil && !filesChanged && bytes.Equal(cached.hash, hash[:]) {
+		// touched, but not changed
+		cached.modTime = info.ModTime()
+		cached.size = info.Size()
+
+		return cached, nil
+	}
+
+	entity, files, err := parse(name, path, data)
+	if err != nil {
+		return cached, err
+	}
+
+	m := &manifest{
+		modTime:   info.ModTime(),
+		size:      info.Size(),
+		hash:      hash[:],
+		entity:    entity,
+		files:     files,
+		filesHash: hashFiles(files),
+	}
+
+	d.manifests[path] = m
+
+	return m, nil
+}
+
+// hashFiles returns the hash of the contents of the files, or nil if one of
+// them cannot be read.
+func hashFiles(paths []string) []byte {
+	h := sha256.New()
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		h.Write(data)
+	}
+
+	return h.Sum(nil)
+}
+
+// manifestName returns the entity name for a manifest file, and false if the
+// file is not a manifest.
+func manifestName(info os.FileInfo) (string, bool) {
+	if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+		return "", false
+	}
+
+	ext := strings.ToLower(filepath.Ext(info.Name()))
+
+	switch ext {
+	case ".yaml", ".yml", ".json":
+		return strings.TrimSuffix(info.Name(), filepath.Ext(info.Name())), true
+	default:
+		return "", false
+	}
+}
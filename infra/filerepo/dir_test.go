@@ -0,0 +1,240 @@
+package filerepo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func tempManifestDir(t *testing.T) string {
+	dir := tempDir(t)
+
+	for _, subdir := range []string{ServicesDir, FrontendsDir} {
+		if err := os.Mkdir(filepath.Join(dir, subdir), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestNewDirShouldReturnErrorOnMissingPath(t *testing.T) {
+	d, err := NewDir("", logger)
+
+	assert.Nil(t, d)
+	assert.Equal(t, ErrMissingPath, err)
+}
+
+func TestNewDirShouldReturnErrorOnMissingDirectory(t *testing.T) {
+	d, err := NewDir("does-not-exist", logger)
+
+	assert.Nil(t, d)
+	assert.NotNil(t, err)
+}
+
+func TestNewDir(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "api.crt", "CERT")
+	writeFile(t, dir, "api.key", "KEY")
+	writeFile(t, dir, "services/api.yaml", "servers:\n- http://10.0.0.1:8080\n")
+	writeFile(t, dir, "services/web.json", `{"servers": ["http://10.0.0.2:8080"], "healthCheck": {"path": "/healthz"}}`)
+	writeFile(t, dir, "services/README.md", "not a manifest")
+	writeFile(t, dir, "frontends/api.yml", `
+url: https://api.example.com
+service: api
+certificate:
+  certificate: ../api.crt
+  privateKey: ../api.key
+`)
+
+	d, err := NewDir(dir, logger)
+	assert.Nil(t, err)
+
+	names, _ := d.ServiceRepository().ListServices()
+	assert.Equal(t, []string{"api", "web"}, names)
+
+	s, err := d.ServiceRepository().DescribeService("web")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://10.0.0.2:8080", s.Servers[0].String())
+
+	_, err = d.ServiceRepository().DescribeService("README")
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+
+	o, err := d.ServiceRepository().(interfaces.ServiceOptionsRepository).DescribeServiceOptions("web")
+	assert.Nil(t, err)
+	assert.Equal(t, "/healthz", o.HealthCheck.Path)
+
+	f, err := d.FrontendRepository().DescribeFrontend("api")
+	assert.Nil(t, err)
+	assert.Equal(t, "api", f.ServiceName)
+	assert.Equal(t, []byte("CERT"), f.Certificate.Certificate)
+
+	_, err = d.FrontendRepository().DescribeFrontend("web")
+	assert.Equal(t, interfaces.ErrUnknownFrontend, err)
+}
+
+func TestNewDirShouldAcceptMissingSubdirectories(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	d, err := NewDir(dir, logger)
+	assert.Nil(t, err)
+
+	names, _ := d.ServiceRepository().ListServices()
+	assert.Empty(t, names)
+}
+
+func TestDirReloadShouldPublishAddedChangedAndRemovedManifests(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "services/api.yaml", "servers:\n- http://10.0.0.1:8080\n")
+	writeFile(t, dir, "frontends/api.yaml", "url: http://api.example.com\nservice: api\n")
+
+	d, _ := NewDir(dir, logger)
+
+	serviceEvents := d.ServiceRepository().Subscribe()
+	frontendEvents := d.FrontendRepository().Subscribe()
+
+	// touching a file without changing it does not publish an event
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "services/api.yaml"), later, later)
+
+	writeFile(t, dir, "services/web.yaml", "servers:\n- http://10.0.0.2:8080\n")
+	writeFile(t, dir, "frontends/api.yaml", "url: http://api2.example.com\nservice: api\n")
+
+	assert.Nil(t, d.Reload())
+
+	assert.Len(t, serviceEvents, 1)
+	se := <-serviceEvents
+	assert.Equal(t, "web", se.Name)
+	assert.Equal(t, interfaces.EventCreated, se.Kind)
+
+	assert.Len(t, frontendEvents, 1)
+	fe := <-frontendEvents
+	assert.Equal(t, "api", fe.Name)
+	assert.Equal(t, interfaces.EventUpdated, fe.Kind)
+	assert.Equal(t, "http://api2.example.com", fe.Frontend.URL.String())
+
+	os.Remove(filepath.Join(dir, "frontends/api.yaml"))
+
+	assert.Nil(t, d.Reload())
+
+	fe = <-frontendEvents
+	assert.Equal(t, "api", fe.Name)
+	assert.Equal(t, interfaces.EventDeleted, fe.Kind)
+	// created on load, updated, deleted
+	assert.Equal(t, uint64(3), fe.Revision)
+}
+
+func TestDirReloadShouldPublishRotatedCertificates(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "api.crt", "CERT")
+	writeFile(t, dir, "api.key", "KEY")
+	writeFile(t, dir, "frontends/api.yaml", `
+url: https://api.example.com
+service: api
+certificate:
+  certificate: ../api.crt
+  privateKey: ../api.key
+`)
+
+	d, _ := NewDir(dir, logger)
+
+	events := d.FrontendRepository().Subscribe()
+
+	// unchanged certificate files: no events
+	assert.Nil(t, d.Reload())
+	assert.Len(t, events, 0)
+
+	writeFile(t, dir, "api.crt", "ROTATED CERT")
+	writeFile(t, dir, "api.key", "ROTATED KEY")
+
+	assert.Nil(t, d.Reload())
+
+	assert.Len(t, events, 1)
+	e := <-events
+	assert.Equal(t, "api", e.Name)
+	assert.Equal(t, interfaces.EventUpdated, e.Kind)
+	assert.Equal(t, []byte("ROTATED CERT"), e.Frontend.Certificate.Certificate)
+	assert.Equal(t, []byte("ROTATED KEY"), e.Frontend.Certificate.PrivateKey)
+}
+
+func TestDirReloadShouldKeepEntityOnInvalidManifest(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "services/api.yaml", "servers:\n- http://10.0.0.1:8080\n")
+
+	d, _ := NewDir(dir, logger)
+
+	events := d.ServiceRepository().Subscribe()
+
+	writeFile(t, dir, "services/api.yaml", "servers: [")
+	writeFile(t, dir, "services/new.yaml", "servers: [")
+
+	assert.Nil(t, d.Reload())
+
+	assert.Len(t, events, 0)
+
+	names, _ := d.ServiceRepository().ListServices()
+	assert.Equal(t, []string{"api"}, names)
+}
+
+func TestDirReloadShouldKeepEntitiesOnUnreadableDirectory(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "services/api.yaml", "servers:\n- http://10.0.0.1:8080\n")
+
+	d, _ := NewDir(dir, logger)
+
+	events := d.ServiceRepository().Subscribe()
+
+	// a file in place of the directory cannot be read as a directory
+	os.RemoveAll(filepath.Join(dir, ServicesDir))
+	writeFile(t, dir, ServicesDir, "not a directory")
+
+	assert.Error(t, d.Reload())
+
+	assert.Len(t, events, 0)
+
+	names, _ := d.ServiceRepository().ListServices()
+	assert.Equal(t, []string{"api"}, names)
+}
+
+func TestDirWatch(t *testing.T) {
+	dir := tempManifestDir(t)
+	defer os.RemoveAll(dir)
+
+	d, _ := NewDir(dir, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Equal(t, ErrInvalidInterval, d.Watch(ctx, 0))
+
+	events := d.ServiceRepository().Subscribe()
+
+	go d.Watch(ctx, 10*time.Millisecond)
+
+	writeFile(t, dir, "services/api.yaml", "servers:\n- http://10.0.0.1:8080\n")
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "api", e.Name)
+		assert.Equal(t, interfaces.EventCreated, e.Kind)
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+}
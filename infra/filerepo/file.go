@@ -0,0 +1,209 @@
+// Package filerepo provides service and frontend repositories backed by YAML
+// or JSON files: either a single configuration file, or a directory holding
+// one manifest per service and frontend.
+package filerepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// Errors
+var (
+	ErrMissingPath           = errors.New("missing path")
+	ErrInvalidInterval       = errors.New("invalid interval, must be greater than 0")
+	ErrMissingMiddlewareName = errors.New("missing middleware name")
+)
+
+// File loads services and frontends from a configuration file. The file
+// contains a services and a frontends section, each mapping names to their
+// definition:
+//
+//	services:
+//	  api:
+//	    servers:
+//	    - http://10.0.0.1:8080?weight=2
+//	    - http://10.0.0.2:8080
+//	    strategy: weighted-round-robin
+//	    healthCheck:
+//	      path: /healthz
+//	      interval: 10s
+//	    outlierDetection:
+//	      consecutiveErrors: 5
+//	frontends:
+//	  api:
+//	    url: https://api.example.com
+//	    service: api
+//	    certificate:
+//	      certificate: certs/api.crt
+//	      privateKey: certs/api.key
+//	    redirectStatus: 308
+//	    securityHeaders:
+//	      hsts:
+//	        maxAge: 8760h
+//	        includeSubDomains: true
+//	      contentTypeNosniff: true
+//	      frameOptions: DENY
+//	    middlewares:
+//	    - name: responseHeaders
+//	      options:
+//	        Cache-Control: no-store
+//	  api-v2:
+//	    url: https://api.example.com/v2
+//	    service: api
+//	    pathRewrite:
+//	      stripPrefix: /v2
+//	  www:
+//	    url: https://www.example.com
+//	    service: api
+//	    autoTLS: true
+//
+// Files with a .json extension are parsed as JSON, all others as YAML.
+type File struct {
+	*store
+
+	path   string
+	logger interfaces.Logger
+
+	// reloadMu serializes reloads, so an older load never replaces a newer
+	// one
+	reloadMu sync.Mutex
+}
+
+// NewFile creates a new File and loads the configuration file at path.
+func NewFile(path string, logger interfaces.Logger) (*File, error) {
+	if path == "" {
+		return nil, ErrMissingPath
+	}
+
+	f := &File{
+		store:  newStore(),
+		path:   path,
+		logger: logger,
+	}
+
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Reload reads the configuration file and publishes events for all services
+// and frontends that were created, updated or deleted since the last load.
+// If the file cannot be loaded the current configuration is kept.
+func (f *File) Reload() error {
+	f.reloadMu.Lock()
+	defer f.reloadMu.Unlock()
+
+	loadedServices, loadedServiceOptions, loadedFrontends, loadedFrontendOptions, err := f.load()
+	if err != nil {
+		return err
+	}
+
+	f.replace(loadedServices, loadedServiceOptions, loadedFrontends, loadedFrontendOptions)
+
+	return nil
+}
+
+// Watch reloads the configuration file at the provided interval until ctx is
+// done. Certificate files are read on each reload as well, so changes to
+// them are detected too. Reload errors are logged.
+func (f *File) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			if err := f.Reload(); err != nil {
+				f.logger.
+					WithError(err).
+					WithField("path", f.path).
+					Error("reloading configuration file")
+			}
+		}
+	}
+}
+
+func (f *File) load() (
+	map[string]*services.Service,
+	map[string]*interfaces.ServiceOptions,
+	map[string]*frontends.Frontend,
+	map[string]*interfaces.FrontendOptions,
+	error) {
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	c := &config{}
+	if err := unmarshal(f.path, data, c); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	loadedServices := make(map[string]*services.Service, len(c.Services))
+	loadedServiceOptions := make(map[string]*interfaces.ServiceOptions)
+
+	for name, sc := range c.Services {
+		if sc == nil {
+			sc = &serviceConfig{}
+		}
+
+		loadedServices[name], err = sc.service(name)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("service %s: %v", name, err)
+		}
+
+		options, err := sc.options()
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("service %s: %v", name, err)
+		}
+
+		if options != nil {
+			loadedServiceOptions[name] = options
+		}
+	}
+
+	dir := filepath.Dir(f.path)
+	loadedFrontends := make(map[string]*frontends.Frontend, len(c.Frontends))
+	loadedFrontendOptions := make(map[string]*interfaces.FrontendOptions)
+
+	for name, fc := range c.Frontends {
+		if fc == nil {
+			fc = &frontendConfig{}
+		}
+
+		loadedFrontends[name], err = fc.frontend(name, dir)
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("frontend %s: %v", name, err)
+		}
+
+		options, err := fc.options()
+		if err != nil {
+			return nil, nil, nil, nil, fmt.Errorf("frontend %s: %v", name, err)
+		}
+
+		if options != nil {
+			loadedFrontendOptions[name] = options
+		}
+	}
+
+	return loadedServices, loadedServiceOptions, loadedFrontends, loadedFrontendOptions, nil
+}
@@ -0,0 +1,538 @@
+package filerepo
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+const testYAML = `
+services:
+  api:
+    servers:
+    - http://10.0.0.1:8080
+    - http://10.0.0.2:8080
+  web:
+    servers:
+    - http://10.0.0.3:8080
+frontends:
+  api:
+    url: https://api.example.com
+    service: api
+    certificate:
+      certificate: api.crt
+      privateKey: api.key
+  web:
+    url: http://www.example.com
+    service: web
+`
+
+const testJSON = `{
+  "services": {
+    "api": {"servers": ["http://10.0.0.1:8080"]}
+  },
+  "frontends": {
+    "api": {"url": "http://api.example.com", "service": "api"}
+  }
+}`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "filerepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestNewFileShouldReturnErrorOnMissingPath(t *testing.T) {
+	f, err := NewFile("", logger)
+
+	assert.Nil(t, f)
+	assert.Equal(t, ErrMissingPath, err)
+}
+
+func TestNewFileShouldReturnErrorOnMissingFile(t *testing.T) {
+	f, err := NewFile("does-not-exist.yaml", logger)
+
+	assert.Nil(t, f)
+	assert.NotNil(t, err)
+}
+
+func TestNewFileShouldReturnErrorOnMissingCertificate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.yaml", testYAML)
+
+	f, err := NewFile(path, logger)
+
+	assert.Nil(t, f)
+	assert.NotNil(t, err)
+}
+
+func TestNewFileYAML(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "api.crt", "CERT")
+	writeFile(t, dir, "api.key", "KEY")
+	path := writeFile(t, dir, "proxy.yaml", testYAML)
+
+	f, err := NewFile(path, logger)
+	assert.Nil(t, err)
+
+	sr := f.ServiceRepository()
+
+	names, err := sr.ListServices()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api", "web"}, names)
+
+	s, err := sr.DescribeService("api")
+	assert.Nil(t, err)
+	assert.Len(t, s.Servers, 2)
+
+	_, err = sr.DescribeService("unknown")
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+
+	fr := f.FrontendRepository()
+
+	names, err = fr.ListFrontends()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api", "web"}, names)
+
+	fe, err := fr.DescribeFrontend("api")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", fe.URL.String())
+	assert.Equal(t, "api", fe.ServiceName)
+	assert.Equal(t, []byte("CERT"), fe.Certificate.Certificate)
+	assert.Equal(t, []byte("KEY"), fe.Certificate.PrivateKey)
+
+	fe, err = fr.DescribeFrontend("web")
+	assert.Nil(t, err)
+	assert.Nil(t, fe.Certificate)
+
+	_, err = fr.DescribeFrontend("unknown")
+	assert.Equal(t, interfaces.ErrUnknownFrontend, err)
+}
+
+func TestNewFileJSON(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, err := NewFile(path, logger)
+	assert.Nil(t, err)
+
+	fe, err := f.FrontendRepository().DescribeFrontend("api")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://api.example.com", fe.URL.String())
+}
+
+func TestReloadShouldPublishChangedEntitiesOnly(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	serviceEvents := f.ServiceRepository().Subscribe()
+	frontendEvents := f.FrontendRepository().Subscribe()
+
+	// unchanged file: no events
+	assert.Nil(t, f.Reload())
+
+	writeFile(t, dir, "proxy.json", `{
+  "services": {
+    "api": {"servers": ["http://10.0.0.1:8080"]},
+    "web": {"servers": ["http://10.0.0.3:8080"]}
+  },
+  "frontends": {
+    "api": {"url": "http://api2.example.com", "service": "api"}
+  }
+}`)
+
+	assert.Nil(t, f.Reload())
+
+	assert.Len(t, serviceEvents, 1)
+	se := <-serviceEvents
+	assert.Equal(t, "web", se.Name)
+	assert.Equal(t, interfaces.EventCreated, se.Kind)
+	assert.Equal(t, uint64(1), se.Revision)
+	assert.NotNil(t, se.Service)
+
+	assert.Len(t, frontendEvents, 1)
+	fe := <-frontendEvents
+	assert.Equal(t, "api", fe.Name)
+	assert.Equal(t, interfaces.EventUpdated, fe.Kind)
+	assert.Equal(t, "http://api2.example.com", fe.Frontend.URL.String())
+
+	writeFile(t, dir, "proxy.json", `{"services": {"api": {"servers": ["http://10.0.0.1:8080"]}}}`)
+
+	assert.Nil(t, f.Reload())
+
+	se = <-serviceEvents
+	assert.Equal(t, "web", se.Name)
+	assert.Equal(t, interfaces.EventDeleted, se.Kind)
+	assert.Equal(t, uint64(2), se.Revision)
+
+	fe = <-frontendEvents
+	assert.Equal(t, "api", fe.Name)
+	assert.Equal(t, interfaces.EventDeleted, fe.Kind)
+}
+
+func TestServiceOptions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.yaml", `
+services:
+  api:
+    servers:
+    - http://10.0.0.1:8080
+    healthCheck:
+      path: /healthz
+      interval: 5s
+      unhealthyThreshold: 2
+  web:
+    servers:
+    - http://10.0.0.2:8080
+  legacy:
+    servers:
+    - http://10.0.0.5:8080
+    stickySessions:
+      ttl: 1h
+  admin:
+    servers:
+    - http://10.0.0.3:8080?weight=2
+    - http://10.0.0.4:8080
+    strategy: consistent-hash
+    hashPolicy:
+      header: X-User
+    outlierDetection:
+      consecutiveErrors: 3
+      baseEjectionTime: 10s
+`)
+
+	f, err := NewFile(path, logger)
+	assert.Nil(t, err)
+
+	sr := f.ServiceRepository().(interfaces.ServiceOptionsRepository)
+
+	o, err := sr.DescribeServiceOptions("api")
+	assert.Nil(t, err)
+	assert.Equal(t, &interfaces.HealthCheck{
+		Path:               "/healthz",
+		Interval:           5 * time.Second,
+		UnhealthyThreshold: 2,
+	}, o.HealthCheck)
+
+	assert.Nil(t, o.OutlierDetection)
+
+	o, err = sr.DescribeServiceOptions("web")
+	assert.Nil(t, err)
+	assert.Nil(t, o)
+
+	o, err = sr.DescribeServiceOptions("legacy")
+	assert.Nil(t, err)
+	assert.Equal(t, &interfaces.StickySessions{TTL: time.Hour}, o.StickySessions)
+
+	o, err = sr.DescribeServiceOptions("admin")
+	assert.Nil(t, err)
+	assert.Nil(t, o.HealthCheck)
+	assert.Equal(t, interfaces.StrategyConsistentHash, o.Strategy)
+	assert.Equal(t, &interfaces.HashPolicy{Header: "X-User"}, o.HashPolicy)
+	assert.Equal(t, &interfaces.OutlierDetection{
+		ConsecutiveErrors: 3,
+		BaseEjectionTime:  10 * time.Second,
+	}, o.OutlierDetection)
+
+	_, err = sr.DescribeServiceOptions("unknown")
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+}
+
+func TestNewFileShouldReturnErrorOnInvalidHealthCheck(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", `{
+  "services": {
+    "api": {"servers": ["http://10.0.0.1:8080"], "healthCheck": {"interval": "often"}}
+  }
+}`)
+
+	f, err := NewFile(path, logger)
+
+	assert.Nil(t, f)
+	assert.NotNil(t, err)
+}
+
+func TestReloadShouldPublishChangedServiceOptions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	serviceEvents := f.ServiceRepository().Subscribe()
+
+	writeFile(t, dir, "proxy.json", `{
+  "services": {
+    "api": {"servers": ["http://10.0.0.1:8080"], "healthCheck": {"path": "/healthz"}}
+  }
+}`)
+
+	assert.Nil(t, f.Reload())
+
+	assert.Len(t, serviceEvents, 1)
+	se := <-serviceEvents
+	assert.Equal(t, "api", se.Name)
+	assert.Equal(t, interfaces.EventUpdated, se.Kind)
+}
+
+func TestFrontendOptions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.yaml", `
+frontends:
+  api:
+    url: https://api.example.com
+    service: api
+    autoTLS: true
+    redirectStatus: 307
+    skipChallengeRedirect: true
+    securityHeaders:
+      hsts:
+        maxAge: 8760h
+        includeSubDomains: true
+        preload: true
+      contentTypeNosniff: true
+      frameOptions: DENY
+      headers:
+        Permissions-Policy: geolocation=()
+    middlewares:
+    - name: responseHeaders
+      options:
+        Cache-Control: no-store
+    - name: rateLimit
+      options:
+        rate: 10
+    pathRewrite:
+      stripPrefix: /api
+      regex: ^/v1/(.*)$
+      replacement: /$1
+      addPrefix: /internal
+  web:
+    url: http://www.example.com
+    service: web
+`)
+
+	f, err := NewFile(path, logger)
+	assert.Nil(t, err)
+
+	fr := f.FrontendRepository().(interfaces.FrontendOptionsRepository)
+
+	o, err := fr.DescribeFrontendOptions("api")
+	assert.Nil(t, err)
+	assert.Equal(t, &interfaces.FrontendOptions{
+		AutoTLS:               true,
+		RedirectStatus:        307,
+		SkipChallengeRedirect: true,
+		SecurityHeaders: &interfaces.SecurityHeaders{
+			HSTS: &interfaces.HSTS{
+				MaxAge:            8760 * time.Hour,
+				IncludeSubDomains: true,
+				Preload:           true,
+			},
+			ContentTypeNosniff: true,
+			FrameOptions:       "DENY",
+			Headers:            map[string]string{"Permissions-Policy": "geolocation=()"},
+		},
+		Middlewares: []*interfaces.MiddlewareConfig{
+			{Name: "responseHeaders", Options: map[string]string{"Cache-Control": "no-store"}},
+			{Name: "rateLimit", Options: map[string]string{"rate": "10"}},
+		},
+		PathRewrite: &interfaces.PathRewrite{
+			StripPrefix: "/api",
+			Regex:       "^/v1/(.*)$",
+			Replacement: "/$1",
+			AddPrefix:   "/internal",
+		},
+	}, o)
+
+	o, err = fr.DescribeFrontendOptions("web")
+	assert.Nil(t, err)
+	assert.Nil(t, o)
+
+	_, err = fr.DescribeFrontendOptions("unknown")
+	assert.Equal(t, interfaces.ErrUnknownFrontend, err)
+}
+
+func TestNewFileShouldReturnErrorOnInvalidFrontendOptions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.yaml", `
+frontends:
+  api:
+    url: https://api.example.com
+    service: api
+    securityHeaders:
+      hsts:
+        maxAge: forever
+`)
+
+	_, err := NewFile(path, logger)
+	assert.Error(t, err)
+}
+
+func TestNewFileShouldReturnErrorOnMissingMiddlewareName(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.yaml", `
+frontends:
+  api:
+    url: https://api.example.com
+    service: api
+    middlewares:
+    - options:
+        rate: 10
+`)
+
+	_, err := NewFile(path, logger)
+	assert.Error(t, err)
+}
+
+func TestReloadShouldPublishChangedFrontendOptions(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	frontendEvents := f.FrontendRepository().Subscribe()
+
+	writeFile(t, dir, "proxy.json", `{
+  "services": {
+    "api": {"servers": ["http://10.0.0.1:8080"]}
+  },
+  "frontends": {
+    "api": {"url": "http://api.example.com", "service": "api", "autoTLS": true}
+  }
+}`)
+
+	assert.Nil(t, f.Reload())
+
+	assert.Len(t, frontendEvents, 1)
+	fe := <-frontendEvents
+	assert.Equal(t, "api", fe.Name)
+	assert.Equal(t, interfaces.EventUpdated, fe.Kind)
+}
+
+func TestReloadShouldKeepConfigurationOnError(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	writeFile(t, dir, "proxy.json", "{")
+
+	assert.NotNil(t, f.Reload())
+
+	names, _ := f.ServiceRepository().ListServices()
+	assert.Equal(t, []string{"api"}, names)
+}
+
+func TestConcurrentReloadsShouldPublishChangesOnce(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	events := f.ServiceRepository().Subscribe()
+
+	writeFile(t, dir, "proxy.json", `{}`)
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			f.Reload()
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Len(t, events, 1)
+}
+
+func TestWatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := writeFile(t, dir, "proxy.json", testJSON)
+
+	f, _ := NewFile(path, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.Equal(t, ErrInvalidInterval, f.Watch(ctx, 0))
+
+	go f.Watch(ctx, 10*time.Millisecond)
+
+	events := f.ServiceRepository().Subscribe()
+
+	writeFile(t, dir, "proxy.json", `{}`)
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "api", e.Name)
+		assert.Equal(t, interfaces.EventDeleted, e.Kind)
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+}
@@ -0,0 +1,57 @@
+package filerepo
+
+import (
+	"sort"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+type frontendRepository struct {
+	s *store
+}
+
+func (r *frontendRepository) ListFrontends() ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	names := make([]string, 0, len(r.s.frontends))
+	for name := range r.s.frontends {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (r *frontendRepository) DescribeFrontend(name string) (*frontends.Frontend, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	frontend, found := r.s.frontends[name]
+	if !found {
+		return nil, interfaces.ErrUnknownFrontend
+	}
+
+	return frontend, nil
+}
+
+func (r *frontendRepository) DescribeFrontendOptions(name string) (*interfaces.FrontendOptions, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, found := r.s.frontends[name]; !found {
+		return nil, interfaces.ErrUnknownFrontend
+	}
+
+	return r.s.frontendOptions[name], nil
+}
+
+func (r *frontendRepository) Subscribe() <-chan interfaces.FrontendEvent {
+	return r.s.frontendEvents.Subscribe()
+}
+
+func (r *frontendRepository) Unsubscribe(events <-chan interfaces.FrontendEvent) {
+	r.s.frontendEvents.Unsubscribe(events)
+}
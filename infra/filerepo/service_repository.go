@@ -0,0 +1,57 @@
+package filerepo
+
+import (
+	"sort"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+type serviceRepository struct {
+	s *store
+}
+
+func (r *serviceRepository) ListServices() ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	names := make([]string, 0, len(r.s.services))
+	for name := range r.s.services {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (r *serviceRepository) DescribeService(name string) (*services.Service, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	service, found := r.s.services[name]
+	if !found {
+		return nil, interfaces.ErrUnknownService
+	}
+
+	return service, nil
+}
+
+func (r *serviceRepository) DescribeServiceOptions(name string) (*interfaces.ServiceOptions, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, found := r.s.services[name]; !found {
+		return nil, interfaces.ErrUnknownService
+	}
+
+	return r.s.serviceOptions[name], nil
+}
+
+func (r *serviceRepository) Subscribe() <-chan interfaces.ServiceEvent {
+	return r.s.serviceEvents.Subscribe()
+}
+
+func (r *serviceRepository) Unsubscribe(events <-chan interfaces.ServiceEvent) {
+	r.s.serviceEvents.Unsubscribe(events)
+}
@@ -0,0 +1,210 @@
+package filerepo
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/infra/broadcast"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// store holds the loaded services and frontends, and publishes events for
+// the changes made to them.
+type store struct {
+	mu              sync.RWMutex
+	services        map[string]*services.Service
+	serviceOptions  map[string]*interfaces.ServiceOptions
+	frontends       map[string]*frontends.Frontend
+	frontendOptions map[string]*interfaces.FrontendOptions
+
+	serviceEvents  broadcast.ServiceEvents
+	frontendEvents broadcast.FrontendEvents
+}
+
+func newStore() *store {
+	return &store{
+		services:        make(map[string]*services.Service),
+		serviceOptions:  make(map[string]*interfaces.ServiceOptions),
+		frontends:       make(map[string]*frontends.Frontend),
+		frontendOptions: make(map[string]*interfaces.FrontendOptions),
+	}
+}
+
+// ServiceRepository returns the loaded services.
+func (s *store) ServiceRepository() interfaces.ServiceWatcher {
+	return &serviceRepository{s: s}
+}
+
+// FrontendRepository returns the loaded frontends.
+func (s *store) FrontendRepository() interfaces.FrontendWatcher {
+	return &frontendRepository{s: s}
+}
+
+// replace replaces all services, all frontends and their options, and
+// publishes events for the ones that were created, updated or deleted. A
+// change to the options of a service or frontend counts as an update of the
+// service or frontend.
+func (s *store) replace(
+	loadedServices map[string]*services.Service,
+	loadedServiceOptions map[string]*interfaces.ServiceOptions,
+	loadedFrontends map[string]*frontends.Frontend,
+	loadedFrontendOptions map[string]*interfaces.FrontendOptions) {
+	s.mu.Lock()
+
+	serviceEvents := diffServices(s.services, loadedServices)
+	serviceEvents = append(serviceEvents,
+		diffServiceOptions(s.serviceOptions, loadedServiceOptions, s.services, loadedServices)...)
+	frontendEvents := diffFrontends(s.frontends, loadedFrontends)
+	frontendEvents = append(frontendEvents,
+		diffFrontendOptions(s.frontendOptions, loadedFrontendOptions, s.frontends, loadedFrontends)...)
+
+	s.services = loadedServices
+	s.serviceOptions = loadedServiceOptions
+	s.frontends = loadedFrontends
+	s.frontendOptions = loadedFrontendOptions
+
+	s.mu.Unlock()
+
+	// publish outside the lock, so subscribers are able to describe
+	for _, event := range serviceEvents {
+		s.serviceEvents.Publish(event)
+	}
+
+	for _, event := range frontendEvents {
+		s.frontendEvents.Publish(event)
+	}
+}
+
+func diffServices(current, loaded map[string]*services.Service) []interfaces.ServiceEvent {
+	var events []interfaces.ServiceEvent
+
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+
+	for name := range loaded {
+		names[name] = true
+	}
+
+	for _, name := range sortedNames(names) {
+		c, l := current[name], loaded[name]
+
+		switch {
+		case l == nil:
+			events = append(events, interfaces.ServiceEvent{Name: name, Kind: interfaces.EventDeleted})
+		case c == nil:
+			events = append(events, interfaces.ServiceEvent{Name: name, Kind: interfaces.EventCreated, Service: l})
+		case !reflect.DeepEqual(c, l):
+			events = append(events, interfaces.ServiceEvent{Name: name, Kind: interfaces.EventUpdated, Service: l})
+		}
+	}
+
+	return events
+}
+
+// diffServiceOptions returns update events for the services that exist
+// before and after loading, are equal themselves, but have changed options.
+func diffServiceOptions(
+	current, loaded map[string]*interfaces.ServiceOptions,
+	currentServices, loadedServices map[string]*services.Service) []interfaces.ServiceEvent {
+	var events []interfaces.ServiceEvent
+
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+
+	for name := range loaded {
+		names[name] = true
+	}
+
+	for _, name := range sortedNames(names) {
+		c, l := currentServices[name], loadedServices[name]
+
+		if c == nil || l == nil || !reflect.DeepEqual(c, l) {
+			// created, deleted or updated already
+			continue
+		}
+
+		if !reflect.DeepEqual(current[name], loaded[name]) {
+			events = append(events, interfaces.ServiceEvent{Name: name, Kind: interfaces.EventUpdated, Service: l})
+		}
+	}
+
+	return events
+}
+
+func diffFrontends(current, loaded map[string]*frontends.Frontend) []interfaces.FrontendEvent {
+	var events []interfaces.FrontendEvent
+
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+
+	for name := range loaded {
+		names[name] = true
+	}
+
+	for _, name := range sortedNames(names) {
+		c, l := current[name], loaded[name]
+
+		switch {
+		case l == nil:
+			events = append(events, interfaces.FrontendEvent{Name: name, Kind: interfaces.EventDeleted})
+		case c == nil:
+			events = append(events, interfaces.FrontendEvent{Name: name, Kind: interfaces.EventCreated, Frontend: l})
+		case !reflect.DeepEqual(c, l):
+			events = append(events, interfaces.FrontendEvent{Name: name, Kind: interfaces.EventUpdated, Frontend: l})
+		}
+	}
+
+	return events
+}
+
+// diffFrontendOptions returns update events for the frontends that exist
+// before and after loading, are equal themselves, but have changed options.
+func diffFrontendOptions(
+	current, loaded map[string]*interfaces.FrontendOptions,
+	currentFrontends, loadedFrontends map[string]*frontends.Frontend) []interfaces.FrontendEvent {
+	var events []interfaces.FrontendEvent
+
+	names := make(map[string]bool)
+	for name := range current {
+		names[name] = true
+	}
+
+	for name := range loaded {
+		names[name] = true
+	}
+
+	for _, name := range sortedNames(names) {
+		c, l := currentFrontends[name], loadedFrontends[name]
+
+		if c == nil || l == nil || !reflect.DeepEqual(c, l) {
+			// created, deleted or updated already
+			continue
+		}
+
+		if !reflect.DeepEqual(current[name], loaded[name]) {
+			events = append(events, interfaces.FrontendEvent{Name: name, Kind: interfaces.EventUpdated, Frontend: l})
+		}
+	}
+
+	return events
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
+}
@@ -0,0 +1,210 @@
+package loadbalancer
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// backend proxies requests to a single server.
+type backend struct {
+	url       *url.URL
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+
+	// weight is guarded by the mu of the service
+	weight int
+
+	mu       sync.Mutex
+	active   int
+	draining bool
+
+	// health check state
+	healthy   bool
+	successes int
+	failures  int
+	lastCheck time.Time
+	lastErr   error
+
+	// outlier detection state
+	outlier      *interfaces.OutlierDetection
+	errors       int
+	ejections    int
+	ejectedUntil time.Time
+}
+
+func newBackend(
+	u *url.URL,
+	outlierDetection *interfaces.OutlierDetection,
+	logger interfaces.Logger) *backend {
+	b := &backend{
+		url:       u,
+		transport: newTransport(),
+		weight:    1,
+		healthy:   true,
+		outlier:   outlierDetection,
+	}
+
+	// health checks use the transport directly, so only proxied requests
+	// are observed
+	b.proxy = httputil.NewSingleHostReverseProxy(u)
+	b.proxy.Transport = &observingTransport{
+		backend: b,
+		next:    b.transport,
+		logger:  logger,
+	}
+
+	return b
+}
+
+// newTransport returns a transport with the settings of
+// http.DefaultTransport. Each backend has its own transport, so its
+// connections can be closed when it is drained.
+func newTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// available reports whether the backend is healthy and not ejected. If the
+// backend is only ejected, the time its ejection ends is returned as well.
+func (b *backend) available(now time.Time) (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.healthy {
+		return false, time.Time{}
+	}
+
+	if now.Before(b.ejectedUntil) {
+		return false, b.ejectedUntil
+	}
+
+	return true, time.Time{}
+}
+
+// acquire registers a request in progress.
+func (b *backend) acquire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.active++
+}
+
+// release unregisters a request in progress, closing the connections of a
+// draining backend once no requests are left.
+func (b *backend) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.active--
+
+	if b.draining && b.active == 0 {
+		b.transport.CloseIdleConnections()
+	}
+}
+
+// drain closes the connections of the backend as soon as no requests are in
+// progress. The backend must no longer be selectable.
+func (b *backend) drain() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.draining = true
+
+	if b.active == 0 {
+		b.transport.CloseIdleConnections()
+	}
+}
+
+// outstanding returns the number of requests in progress.
+func (b *backend) outstanding() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.active
+}
+
+// recordCheck records the result of a health check. It returns whether the
+// backend changed from healthy to unhealthy or vice versa.
+func (b *backend) recordCheck(err error, healthCheck *interfaces.HealthCheck) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastCheck = time.Now()
+	b.lastErr = err
+
+	if err != nil {
+		b.successes = 0
+		b.failures++
+
+		if b.healthy && b.failures >= healthCheck.UnhealthyThreshold {
+			b.healthy = false
+
+			return true
+		}
+
+		return false
+	}
+
+	b.failures = 0
+	b.successes++
+
+	if !b.healthy && b.successes >= healthCheck.HealthyThreshold {
+		b.healthy = true
+
+		return true
+	}
+
+	return false
+}
+
+// resetHealth makes the backend healthy and clears its health check state.
+func (b *backend) resetHealth() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.healthy = true
+	b.successes = 0
+	b.failures = 0
+	b.lastCheck = time.Time{}
+	b.lastErr = nil
+}
+
+func (b *backend) health() *interfaces.BackendHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ejected := time.Now().Before(b.ejectedUntil)
+
+	h := &interfaces.BackendHealth{
+		URL:                  b.url,
+		Healthy:              b.healthy && !ejected,
+		LastCheck:            b.lastCheck,
+		LastError:            b.lastErr,
+		ConsecutiveSuccesses: b.successes,
+		ConsecutiveFailures:  b.failures,
+		Ejected:              ejected,
+		Ejections:            b.ejections,
+		ConsecutiveErrors:    b.errors,
+	}
+
+	if ejected {
+		h.EjectedUntil = b.ejectedUntil
+	}
+
+	return h
+}
@@ -0,0 +1,185 @@
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Health check defaults, used for zero values in an interfaces.HealthCheck.
+const (
+	DefaultHealthCheckPath      = "/"
+	DefaultHealthCheckInterval  = 10 * time.Second
+	DefaultHealthCheckTimeout   = 2 * time.Second
+	DefaultHealthyThreshold     = 2
+	DefaultUnhealthyThreshold   = 3
+	maxHealthCheckResponseBytes = 4096
+)
+
+// healthCheckWithDefaults returns a copy of healthCheck with the defaults
+// applied.
+func healthCheckWithDefaults(healthCheck *interfaces.HealthCheck) (*interfaces.HealthCheck, error) {
+	if healthCheck.Interval < 0 ||
+		healthCheck.Timeout < 0 ||
+		healthCheck.HealthyThreshold < 0 ||
+		healthCheck.UnhealthyThreshold < 0 {
+		return nil, ErrInvalidHealthCheck
+	}
+
+	c := *healthCheck
+
+	if c.Path == "" {
+		c.Path = DefaultHealthCheckPath
+	}
+
+	if c.Interval == 0 {
+		c.Interval = DefaultHealthCheckInterval
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = DefaultHealthCheckTimeout
+	}
+
+	if c.HealthyThreshold == 0 {
+		c.HealthyThreshold = DefaultHealthyThreshold
+	}
+
+	if c.UnhealthyThreshold == 0 {
+		c.UnhealthyThreshold = DefaultUnhealthyThreshold
+	}
+
+	if _, err := url.Parse(c.Path); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// healthChecker periodically checks all backends of a service until it is
+// stopped.
+type healthChecker struct {
+	service *service
+	config  *interfaces.HealthCheck
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newHealthChecker(s *service, config *interfaces.HealthCheck) *healthChecker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &healthChecker{
+		service: s,
+		config:  config,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go c.run()
+
+	return c
+}
+
+// stop stops the health checker and waits for it to return.
+func (c *healthChecker) stop() {
+	c.cancel()
+	<-c.done
+}
+
+func (c *healthChecker) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll()
+
+		select {
+		case <-c.ctx.Done():
+			return
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll checks all backends concurrently.
+func (c *healthChecker) checkAll() {
+	var wg sync.WaitGroup
+
+	for _, b := range c.service.snapshot() {
+		wg.Add(1)
+
+		go func(b *backend) {
+			defer wg.Done()
+
+			err := c.check(b)
+
+			if c.ctx.Err() != nil {
+				// stopped while checking
+				return
+			}
+
+			if b.recordCheck(err, c.config) {
+				c.logTransition(b, err)
+			}
+		}(b)
+	}
+
+	wg.Wait()
+}
+
+// check requests the health check path on the backend.
+func (c *healthChecker) check(b *backend) error {
+	ref, err := url.Parse(c.config.Path)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", b.url.ResolveReference(ref).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.config.Timeout)
+	defer cancel()
+
+	resp, err := b.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	// drain a bit of the body so the connection can be reused
+	io.CopyN(ioutil.Discard, resp.Body, maxHealthCheckResponseBytes)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (c *healthChecker) logTransition(b *backend, err error) {
+	logger := c.service.logger.
+		WithField("service", c.service.name).
+		WithField("server", b.url)
+
+	if err != nil {
+		logger.WithError(err).Warn("ejecting unhealthy server")
+
+		return
+	}
+
+	logger.Info("admitting healthy server")
+}
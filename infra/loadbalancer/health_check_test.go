@@ -0,0 +1,194 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// toggleServer starts a test server that responds with its name, and whose
+// /healthz path fails while healthy is 0.
+func toggleServer(name string, healthy *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" && atomic.LoadInt32(healthy) == 0 {
+			http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Write([]byte(name))
+	}))
+}
+
+var fastHealthCheck = &interfaces.ServiceOptions{
+	HealthCheck: &interfaces.HealthCheck{
+		Path:               "/healthz",
+		Interval:           10 * time.Millisecond,
+		Timeout:            time.Second,
+		HealthyThreshold:   1,
+		UnhealthyThreshold: 1,
+	},
+}
+
+// waitForHealth waits until the server at rawurl has the expected health.
+func waitForHealth(t *testing.T, lb *LoadBalancer, rawurl string, healthy bool) *interfaces.BackendHealth {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		for _, h := range lb.BackendHealth() {
+			if h.URL.String() == rawurl && h.Healthy == healthy && !h.LastCheck.IsZero() {
+				return h
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("server %s did not become healthy=%v", rawurl, healthy)
+
+	return nil
+}
+
+func TestHealthCheckWithDefaults(t *testing.T) {
+	c, err := healthCheckWithDefaults(&interfaces.HealthCheck{Path: "/healthz"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &interfaces.HealthCheck{
+		Path:               "/healthz",
+		Interval:           DefaultHealthCheckInterval,
+		Timeout:            DefaultHealthCheckTimeout,
+		HealthyThreshold:   DefaultHealthyThreshold,
+		UnhealthyThreshold: DefaultUnhealthyThreshold,
+	}, c)
+}
+
+func TestUpsertServiceWithOptionsShouldReturnErrorOnInvalidHealthCheck(t *testing.T) {
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", &interfaces.ServiceOptions{
+		HealthCheck: &interfaces.HealthCheck{Interval: -time.Second},
+	}, mustParse("http://127.0.0.1:8080"))
+
+	assert.Nil(t, h)
+	assert.Equal(t, ErrInvalidHealthCheck, err)
+}
+
+func TestLoadBalancerImplementsHealthReporter(t *testing.T) {
+	var r interfaces.HealthReporter = NewLoadBalancer(logger)
+	var lb interfaces.ConfigurableLoadBalancer = NewLoadBalancer(logger)
+
+	assert.NotNil(t, r)
+	assert.NotNil(t, lb)
+}
+
+func TestHealthCheckShouldEjectAndAdmitServers(t *testing.T) {
+	healthyA, healthyB := int32(1), int32(1)
+
+	a, b := toggleServer("a", &healthyA), toggleServer("b", &healthyB)
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+	defer lb.Close()
+
+	h, err := lb.UpsertServiceWithOptions("api", fastHealthCheck, mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	atomic.StoreInt32(&healthyA, 0)
+
+	health := waitForHealth(t, lb, a.URL, false)
+	assert.True(t, health.Checked)
+	assert.Equal(t, "api", health.ServiceName)
+	assert.NotNil(t, health.LastError)
+	assert.True(t, health.ConsecutiveFailures >= 1)
+
+	for i := 0; i < 4; i++ {
+		_, body := get(t, h)
+		assert.Equal(t, "b", body)
+	}
+
+	atomic.StoreInt32(&healthyA, 1)
+
+	waitForHealth(t, lb, a.URL, true)
+
+	bodies := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		_, body := get(t, h)
+		bodies[body] = true
+	}
+
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, bodies)
+}
+
+func TestHealthCheckShouldRespondServiceUnavailableWithoutHealthyServers(t *testing.T) {
+	healthy := int32(0)
+
+	a := toggleServer("a", &healthy)
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+	defer lb.Close()
+
+	h, err := lb.UpsertServiceWithOptions("api", fastHealthCheck, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	waitForHealth(t, lb, a.URL, false)
+
+	code, _ := get(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestUpsertServiceWithoutHealthCheckShouldAdmitAllServers(t *testing.T) {
+	healthy := int32(0)
+
+	a := toggleServer("a", &healthy)
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+	defer lb.Close()
+
+	h, err := lb.UpsertServiceWithOptions("api", fastHealthCheck, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	waitForHealth(t, lb, a.URL, false)
+
+	_, err = lb.UpsertService("api", mustParse(a.URL))
+	assert.Nil(t, err)
+
+	health := lb.BackendHealth()
+	assert.Len(t, health, 1)
+	assert.True(t, health[0].Healthy)
+	assert.False(t, health[0].Checked)
+
+	_, body := get(t, h)
+	assert.Equal(t, "a", body)
+}
+
+func TestDeleteServiceShouldStopHealthChecks(t *testing.T) {
+	var checks int32
+
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&checks, 1)
+	}))
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", fastHealthCheck, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	lb.DeleteService("api")
+
+	assert.Nil(t, h.(*service).checker)
+	assert.Empty(t, lb.BackendHealth())
+
+	n := atomic.LoadInt32(&checks)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt32(&checks))
+}
@@ -0,0 +1,209 @@
+// Package loadbalancer provides an interfaces.LoadBalancer built on
+// httputil.ReverseProxy.
+package loadbalancer
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingServers     = errors.New("missing servers")
+	ErrInvalidServer      = errors.New("invalid server, must be an absolute URL")
+	ErrInvalidWeight      = errors.New("invalid weight, must be a positive integer")
+	ErrUnknownStrategy    = errors.New("unknown strategy")
+	ErrMissingKey         = errors.New("missing key")
+	ErrInvalidHealthCheck = errors.New("invalid health check, durations and thresholds must not be negative")
+
+	ErrInvalidOutlierDetection = errors.New("invalid outlier detection, durations and thresholds must not be negative")
+)
+
+// LoadBalancer distributes the requests for a service over its healthy
+// servers, using the strategy from the service options, or the server named
+// by the affinity cookie for sticky sessions. Servers are actively health
+// checked if the service options contain a health check. Servers that keep
+// returning 5xx responses or connection errors are ejected for some time.
+type LoadBalancer struct {
+	logger interfaces.Logger
+
+	// key signs the affinity cookies of sticky sessions
+	key []byte
+
+	mu       sync.Mutex
+	services map[string]*service
+}
+
+// NewLoadBalancer creates a new LoadBalancer without any services. Affinity
+// cookies are signed with a random key, so they are only valid for this
+// load balancer.
+func NewLoadBalancer(logger interfaces.Logger) *LoadBalancer {
+	key := make([]byte, keySize)
+
+	if _, err := rand.Read(key); err != nil {
+		// the system random number generator is broken
+		panic(err)
+	}
+
+	lb, _ := NewLoadBalancerWithKey(logger, key)
+
+	return lb
+}
+
+// NewLoadBalancerWithKey creates a new LoadBalancer without any services,
+// that signs affinity cookies with the provided key. Load balancers sharing
+// the key accept each other's affinity cookies.
+func NewLoadBalancerWithKey(logger interfaces.Logger, key []byte) (*LoadBalancer, error) {
+	if len(key) < 1 {
+		return nil, ErrMissingKey
+	}
+
+	return &LoadBalancer{
+		logger:   logger,
+		key:      key,
+		services: make(map[string]*service),
+	}, nil
+}
+
+// UpsertService sets the servers for a service. It returns an http.Handler
+// for the service. Upserting an existing service updates its servers in
+// place and returns the same handler, so handlers already in use keep
+// working. Servers that are removed are drained: they receive no new
+// requests, while requests in progress are allowed to complete.
+func (lb *LoadBalancer) UpsertService(name string, urls ...*url.URL) (http.Handler, error) {
+	return lb.UpsertServiceWithOptions(name, nil, urls...)
+}
+
+// UpsertServiceWithOptions sets the servers and options for a service, see
+// UpsertService. Changing the health check restarts the health checking of
+// the service. Outlier detection uses the defaults unless it is set in the
+// options. Changing the strategy resets its state, such as the round-robin
+// position.
+func (lb *LoadBalancer) UpsertServiceWithOptions(
+	name string,
+	options *interfaces.ServiceOptions,
+	urls ...*url.URL) (http.Handler, error) {
+	if len(urls) < 1 {
+		return nil, ErrMissingServers
+	}
+
+	servers := make([]*server, 0, len(urls))
+
+	for _, u := range urls {
+		if u == nil || !u.IsAbs() {
+			return nil, ErrInvalidServer
+		}
+
+		target, weight, err := parseServer(u)
+		if err != nil {
+			return nil, err
+		}
+
+		servers = append(servers, &server{url: target, weight: weight})
+	}
+
+	if options == nil {
+		options = &interfaces.ServiceOptions{}
+	}
+
+	strat, err := newStrategy(options.Strategy, options.HashPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	var outlierDetection *interfaces.OutlierDetection
+
+	if options.OutlierDetection != nil {
+		outlierDetection, err = outlierDetectionWithDefaults(options.OutlierDetection)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var healthCheck *interfaces.HealthCheck
+
+	if options.HealthCheck != nil {
+		healthCheck, err = healthCheckWithDefaults(options.HealthCheck)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	s, found := lb.services[name]
+	if !found {
+		s = newService(name, lb.logger)
+		lb.services[name] = s
+	}
+
+	var stickySessions *sticky
+	if options.StickySessions != nil {
+		stickySessions = newSticky(name, lb.key, options.StickySessions)
+	}
+
+	s.setOutlierDetection(outlierDetection)
+	s.setStrategy(strategyOptions{name: options.Strategy, hashPolicy: options.HashPolicy}, strat)
+	s.setSticky(stickySessions)
+	s.setServers(servers)
+	s.setHealthCheck(healthCheck)
+
+	return s, nil
+}
+
+// DeleteService deletes the service from the load balancer, stopping its
+// health checks and draining all of its servers. Its handler responds with
+// 503 Service Unavailable from then on.
+func (lb *LoadBalancer) DeleteService(name string) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	s, found := lb.services[name]
+	if !found {
+		return
+	}
+
+	delete(lb.services, name)
+
+	s.setHealthCheck(nil)
+	s.setServers(nil)
+}
+
+// Close stops the health checks of all services.
+func (lb *LoadBalancer) Close() {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	for _, s := range lb.services {
+		s.setHealthCheck(nil)
+	}
+}
+
+// BackendHealth returns the health of all servers, ordered by service name
+// and in the order the servers were provided.
+func (lb *LoadBalancer) BackendHealth() []*interfaces.BackendHealth {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	names := make([]string, 0, len(lb.services))
+	for name := range lb.services {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var health []*interfaces.BackendHealth
+
+	for _, name := range names {
+		health = append(health, lb.services[name].backendHealth()...)
+	}
+
+	return health
+}
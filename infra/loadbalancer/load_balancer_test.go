@@ -0,0 +1,200 @@
+package loadbalancer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+// namedServer starts a test server that responds with its name.
+func namedServer(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+}
+
+func mustParse(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return u
+}
+
+func get(t *testing.T, h http.Handler) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/", nil))
+
+	body, err := ioutil.ReadAll(rec.Body)
+	assert.Nil(t, err)
+
+	return rec.Code, string(body)
+}
+
+func TestLoadBalancerImplementsLoadBalancer(t *testing.T) {
+	var lb interfaces.LoadBalancer = NewLoadBalancer(logger)
+
+	assert.NotNil(t, lb)
+}
+
+func TestUpsertServiceShouldReturnErrorOnMissingServers(t *testing.T) {
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api")
+
+	assert.Nil(t, h)
+	assert.Equal(t, ErrMissingServers, err)
+}
+
+func TestUpsertServiceShouldReturnErrorOnInvalidServer(t *testing.T) {
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api", mustParse("/relative"))
+	assert.Nil(t, h)
+	assert.Equal(t, ErrInvalidServer, err)
+
+	h, err = lb.UpsertService("api", nil)
+	assert.Nil(t, h)
+	assert.Equal(t, ErrInvalidServer, err)
+}
+
+func TestUpsertServiceShouldRoundRobin(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api", mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	var bodies []string
+	for i := 0; i < 4; i++ {
+		code, body := get(t, h)
+		assert.Equal(t, http.StatusOK, code)
+
+		bodies = append(bodies, body)
+	}
+
+	assert.Equal(t, []string{"a", "b", "a", "b"}, bodies)
+}
+
+func TestUpsertServiceShouldUpdateServersInPlace(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h1, err := lb.UpsertService("api", mustParse(a.URL))
+	assert.Nil(t, err)
+
+	h2, err := lb.UpsertService("api", mustParse(b.URL))
+	assert.Nil(t, err)
+
+	assert.True(t, h1 == h2)
+
+	_, body := get(t, h1)
+	assert.Equal(t, "b", body)
+}
+
+func TestUpsertServiceShouldKeepExistingBackends(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api", mustParse(a.URL))
+	assert.Nil(t, err)
+
+	s := h.(*service)
+	kept := s.backends[0]
+
+	_, err = lb.UpsertService("api", mustParse(b.URL), mustParse(a.URL))
+	assert.Nil(t, err)
+
+	assert.Len(t, s.backends, 2)
+	assert.True(t, kept == s.backends[1])
+	assert.False(t, kept.draining)
+}
+
+func TestDeleteServiceShouldRespondServiceUnavailable(t *testing.T) {
+	a := namedServer("a")
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api", mustParse(a.URL))
+	assert.Nil(t, err)
+
+	lb.DeleteService("api")
+
+	code, _ := get(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// deleting an unknown service is a no-op
+	lb.DeleteService("unknown")
+}
+
+func TestDeleteServiceShouldDrainRequestsInProgress(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-unblock
+
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertService("api", mustParse(slow.URL))
+	assert.Nil(t, err)
+
+	b := h.(*service).backends[0]
+
+	done := make(chan string)
+	go func() {
+		_, body := get(t, h)
+		done <- body
+	}()
+
+	<-started
+
+	lb.DeleteService("api")
+	assert.Equal(t, 1, b.outstanding())
+
+	close(unblock)
+
+	select {
+	case body := <-done:
+		assert.Equal(t, "slow", body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request in progress was not completed")
+	}
+
+	assert.Equal(t, 0, b.outstanding())
+}
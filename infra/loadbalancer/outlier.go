@@ -0,0 +1,143 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Outlier detection defaults, used for zero values in an
+// interfaces.OutlierDetection.
+const (
+	DefaultConsecutiveErrors = 5
+	DefaultBaseEjectionTime  = 30 * time.Second
+	DefaultMaxEjectionTime   = 5 * time.Minute
+)
+
+// outlierDetectionWithDefaults returns a copy of outlierDetection with the
+// defaults applied.
+func outlierDetectionWithDefaults(outlierDetection *interfaces.OutlierDetection) (*interfaces.OutlierDetection, error) {
+	if outlierDetection.ConsecutiveErrors < 0 ||
+		outlierDetection.BaseEjectionTime < 0 ||
+		outlierDetection.MaxEjectionTime < 0 {
+		return nil, ErrInvalidOutlierDetection
+	}
+
+	c := *outlierDetection
+
+	if c.ConsecutiveErrors == 0 {
+		c.ConsecutiveErrors = DefaultConsecutiveErrors
+	}
+
+	if c.BaseEjectionTime == 0 {
+		c.BaseEjectionTime = DefaultBaseEjectionTime
+	}
+
+	if c.MaxEjectionTime == 0 {
+		c.MaxEjectionTime = DefaultMaxEjectionTime
+	}
+
+	if c.MaxEjectionTime < c.BaseEjectionTime {
+		c.MaxEjectionTime = c.BaseEjectionTime
+	}
+
+	return &c, nil
+}
+
+// observingTransport records the outcome of each proxied request on its
+// backend.
+type observingTransport struct {
+	backend *backend
+	next    http.RoundTripper
+	logger  interfaces.Logger
+}
+
+func (t *observingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	var ejectionTime time.Duration
+
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// canceled by the client, which says nothing about the backend
+	case err != nil:
+		ejectionTime = t.backend.recordResult(false)
+	default:
+		ejectionTime = t.backend.recordResult(resp.StatusCode < 500)
+	}
+
+	if ejectionTime > 0 {
+		t.logger.
+			WithField("server", t.backend.url).
+			WithField("ejectionTime", ejectionTime).
+			Warn("ejecting outlier server")
+	}
+
+	return resp, err
+}
+
+// recordResult records the outcome of a proxied request. The backend is
+// ejected once the number of consecutive errors reaches the threshold. The
+// ejection time doubles with each consecutive ejection, up to the maximum.
+// It returns the ejection time if the backend was ejected, and 0 otherwise,
+// including when outlier detection is disabled.
+func (b *backend) recordResult(success bool) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.outlier == nil {
+		return 0
+	}
+
+	now := time.Now()
+
+	if success {
+		b.errors = 0
+
+		// forget earlier ejections once the backend behaved for a while
+		if b.ejections > 0 && now.Sub(b.ejectedUntil) > b.outlier.MaxEjectionTime {
+			b.ejections = 0
+		}
+
+		return 0
+	}
+
+	b.errors++
+
+	if b.errors < b.outlier.ConsecutiveErrors || now.Before(b.ejectedUntil) {
+		return 0
+	}
+
+	ejectionTime := b.outlier.BaseEjectionTime
+	for i := 0; i < b.ejections && ejectionTime < b.outlier.MaxEjectionTime; i++ {
+		ejectionTime *= 2
+	}
+
+	if ejectionTime > b.outlier.MaxEjectionTime {
+		ejectionTime = b.outlier.MaxEjectionTime
+	}
+
+	b.ejectedUntil = now.Add(ejectionTime)
+	b.ejections++
+
+	// a single error after the ejection ends ejects the backend again
+	b.errors = b.outlier.ConsecutiveErrors - 1
+
+	return ejectionTime
+}
+
+// setOutlierDetection sets the outlier detection of the backend. Disabling
+// it readmits an ejected backend.
+func (b *backend) setOutlierDetection(outlierDetection *interfaces.OutlierDetection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.outlier = outlierDetection
+
+	if outlierDetection == nil {
+		b.errors = 0
+		b.ejections = 0
+		b.ejectedUntil = time.Time{}
+	}
+}
@@ -0,0 +1,206 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// failingServer starts a test server that responds with its name, or with
+// 500 Internal Server Error while failing is not 0.
+func failingServer(name string, failing *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(failing) != 0 {
+			http.Error(w, "failing", http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Write([]byte(name))
+	}))
+}
+
+func outlierOptions(consecutiveErrors int, baseEjectionTime time.Duration) *interfaces.ServiceOptions {
+	return &interfaces.ServiceOptions{
+		OutlierDetection: &interfaces.OutlierDetection{
+			ConsecutiveErrors: consecutiveErrors,
+			BaseEjectionTime:  baseEjectionTime,
+			MaxEjectionTime:   4 * baseEjectionTime,
+		},
+	}
+}
+
+func TestOutlierDetectionWithDefaults(t *testing.T) {
+	c, err := outlierDetectionWithDefaults(&interfaces.OutlierDetection{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, &interfaces.OutlierDetection{
+		ConsecutiveErrors: DefaultConsecutiveErrors,
+		BaseEjectionTime:  DefaultBaseEjectionTime,
+		MaxEjectionTime:   DefaultMaxEjectionTime,
+	}, c)
+
+	_, err = outlierDetectionWithDefaults(&interfaces.OutlierDetection{ConsecutiveErrors: -1})
+	assert.Equal(t, ErrInvalidOutlierDetection, err)
+}
+
+func TestRecordResultShouldEjectWithExponentialBackoff(t *testing.T) {
+	b := newBackend(mustParse("http://127.0.0.1:8080"), &interfaces.OutlierDetection{
+		ConsecutiveErrors: 2,
+		BaseEjectionTime:  time.Minute,
+		MaxEjectionTime:   3 * time.Minute,
+	}, logger)
+
+	assert.Equal(t, time.Duration(0), b.recordResult(false))
+	assert.Equal(t, time.Minute, b.recordResult(false))
+
+	// errors while ejected do not extend the ejection
+	assert.Equal(t, time.Duration(0), b.recordResult(false))
+
+	ok, until := b.available(time.Now())
+	assert.False(t, ok)
+	assert.False(t, until.IsZero())
+
+	// end the ejection: a single error ejects again, for twice as long
+	b.ejectedUntil = time.Now()
+	assert.Equal(t, 2*time.Minute, b.recordResult(false))
+
+	// limited by the maximum
+	b.ejectedUntil = time.Now()
+	assert.Equal(t, 3*time.Minute, b.recordResult(false))
+
+	// a success resets the consecutive errors
+	b.ejectedUntil = time.Now()
+	assert.Equal(t, time.Duration(0), b.recordResult(true))
+	assert.Equal(t, time.Duration(0), b.recordResult(false))
+
+	h := b.health()
+	assert.True(t, h.Healthy)
+	assert.False(t, h.Ejected)
+	assert.Equal(t, 3, h.Ejections)
+	assert.Equal(t, 1, h.ConsecutiveErrors)
+}
+
+func TestOutlierDetectionShouldBeDisabledByDefault(t *testing.T) {
+	failing := int32(1)
+
+	a := failingServer("a", &failing)
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", &interfaces.ServiceOptions{}, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	for i := 0; i < 2*DefaultConsecutiveErrors; i++ {
+		code, _ := get(t, h)
+		assert.Equal(t, http.StatusInternalServerError, code)
+	}
+
+	assert.False(t, lb.BackendHealth()[0].Ejected)
+}
+
+func TestOutlierDetectionShouldReadmitServerWhenDisabled(t *testing.T) {
+	failing := int32(1)
+
+	a := failingServer("a", &failing)
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", outlierOptions(1, time.Minute), mustParse(a.URL))
+	assert.Nil(t, err)
+
+	get(t, h)
+	assert.True(t, lb.BackendHealth()[0].Ejected)
+
+	_, err = lb.UpsertServiceWithOptions("api", nil, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	assert.False(t, lb.BackendHealth()[0].Ejected)
+
+	atomic.StoreInt32(&failing, 0)
+
+	code, body := get(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "a", body)
+}
+
+func TestOutlierDetectionShouldEjectFailingServer(t *testing.T) {
+	failingA, failingB := int32(1), int32(0)
+
+	a, b := failingServer("a", &failingA), failingServer("b", &failingB)
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", outlierOptions(2, time.Minute), mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	// a fails twice in round-robin order, and is ejected
+	for i := 0; i < 4; i++ {
+		get(t, h)
+	}
+
+	for i := 0; i < 4; i++ {
+		code, body := get(t, h)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "b", body)
+	}
+
+	health := lb.BackendHealth()
+	assert.True(t, health[0].Ejected)
+	assert.False(t, health[0].Healthy)
+	assert.False(t, health[1].Ejected)
+}
+
+func TestOutlierDetectionShouldEjectUnreachableServer(t *testing.T) {
+	a := namedServer("a")
+	unreachable := mustParse(a.URL)
+	a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", outlierOptions(1, time.Minute), unreachable)
+	assert.Nil(t, err)
+
+	code, _ := get(t, h)
+	assert.Equal(t, http.StatusBadGateway, code)
+
+	assert.True(t, lb.BackendHealth()[0].Ejected)
+}
+
+func TestOutlierDetectionShouldOpenCircuitWhenAllServersAreEjected(t *testing.T) {
+	failing := int32(1)
+
+	a := failingServer("a", &failing)
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", outlierOptions(1, 100*time.Millisecond), mustParse(a.URL))
+	assert.Nil(t, err)
+
+	code, _ := get(t, h)
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "http://example.com/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// the server recovers and is admitted again after the ejection time
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(150 * time.Millisecond)
+
+	code, body := get(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "a", body)
+}
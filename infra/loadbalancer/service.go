@@ -0,0 +1,267 @@
+package loadbalancer
+
+import (
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// service is the http.Handler for a service.
+type service struct {
+	name   string
+	logger interfaces.Logger
+
+	mu       sync.RWMutex
+	backends []*backend
+	outlier  *interfaces.OutlierDetection
+
+	strategy        strategy
+	strategyOptions strategyOptions
+	sticky          *sticky
+
+	// checker is guarded by the mu of the LoadBalancer
+	checker *healthChecker
+}
+
+// server is a server URL without the weight parameter, and its weight.
+type server struct {
+	url    *url.URL
+	weight int
+}
+
+// strategyOptions holds the service options that define the strategy.
+type strategyOptions struct {
+	name       interfaces.Strategy
+	hashPolicy *interfaces.HashPolicy
+}
+
+func newService(name string, logger interfaces.Logger) *service {
+	return &service{
+		name:     name,
+		logger:   logger,
+		strategy: &roundRobin{},
+	}
+}
+
+// setServers replaces the backends of the service. Backends for servers that
+// were already present are kept, with their weights updated. The others are
+// drained.
+func (s *service) setServers(servers []*server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]*backend, len(s.backends))
+	for _, b := range s.backends {
+		current[b.url.String()] = b
+	}
+
+	backends := make([]*backend, 0, len(servers))
+
+	for _, srv := range servers {
+		key := srv.url.String()
+
+		b, found := current[key]
+		if found {
+			delete(current, key)
+		} else {
+			b = newBackend(srv.url, s.outlier, s.logger.WithField("service", s.name))
+		}
+
+		b.weight = srv.weight
+
+		backends = append(backends, b)
+	}
+
+	s.backends = backends
+	s.strategy.update(backends)
+
+	// no new requests can select the remaining backends once the lock is
+	// released
+	for _, b := range current {
+		s.logger.
+			WithField("service", s.name).
+			WithField("server", b.url).
+			Debug("draining server")
+
+		b.drain()
+	}
+}
+
+// setOutlierDetection sets the outlier detection of the service and all of
+// its backends.
+func (s *service) setOutlierDetection(outlierDetection *interfaces.OutlierDetection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.outlier = outlierDetection
+
+	for _, b := range s.backends {
+		b.setOutlierDetection(outlierDetection)
+	}
+}
+
+// setStrategy replaces the strategy of the service, unless the options are
+// the same as the current ones.
+func (s *service) setStrategy(options strategyOptions, strat strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reflect.DeepEqual(s.strategyOptions, options) {
+		return
+	}
+
+	strat.update(s.backends)
+
+	s.strategy = strat
+	s.strategyOptions = options
+}
+
+// setSticky enables sticky sessions, or disables them if st is nil.
+func (s *service) setSticky(st *sticky) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sticky = st
+}
+
+// setHealthCheck starts health checking the backends of the service, or
+// stops it if healthCheck is nil. Nothing changes if the health check is
+// the same as the current one.
+func (s *service) setHealthCheck(healthCheck *interfaces.HealthCheck) {
+	if s.checker != nil {
+		if healthCheck != nil && reflect.DeepEqual(s.checker.config, healthCheck) {
+			return
+		}
+
+		s.checker.stop()
+		s.checker = nil
+	}
+
+	if healthCheck == nil {
+		// without health checks all backends receive requests
+		for _, b := range s.snapshot() {
+			b.resetHealth()
+		}
+
+		return
+	}
+
+	s.logger.
+		WithField("service", s.name).
+		WithField("path", healthCheck.Path).
+		WithField("interval", healthCheck.Interval).
+		Debug("starting health checks")
+
+	s.checker = newHealthChecker(s, healthCheck)
+}
+
+// snapshot returns the current backends.
+func (s *service) snapshot() []*backend {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.backends
+}
+
+// selectBackend returns the available backend named by the affinity cookie
+// of the request, or the one picked by the strategy otherwise, and acquires
+// it. For sticky sessions it returns the affinity cookie to set, if the
+// request did not have it. If the service has no available backends it
+// returns nil and the first time an ejected backend becomes available
+// again, if any.
+func (s *service) selectBackend(r *http.Request) (*backend, *http.Cookie, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	var retryAt time.Time
+
+	available := func(b *backend) bool {
+		ok, ejectedUntil := b.available(now)
+
+		if !ejectedUntil.IsZero() && (retryAt.IsZero() || ejectedUntil.Before(retryAt)) {
+			retryAt = ejectedUntil
+		}
+
+		return ok
+	}
+
+	if s.sticky != nil {
+		if id, ok := s.sticky.cookieID(r); ok {
+			for _, b := range s.backends {
+				if s.sticky.matches(id, b) && available(b) {
+					b.acquire()
+
+					return b, nil, time.Time{}
+				}
+			}
+		}
+	}
+
+	b := s.strategy.pick(r, available)
+	if b == nil {
+		return nil, nil, retryAt
+	}
+
+	b.acquire()
+
+	var cookie *http.Cookie
+
+	if s.sticky != nil {
+		cookie = s.sticky.cookie(r, b)
+	}
+
+	return b, cookie, time.Time{}
+}
+
+func (s *service) backendHealth() []*interfaces.BackendHealth {
+	checked := s.checker != nil
+
+	var health []*interfaces.BackendHealth
+
+	for _, b := range s.snapshot() {
+		h := b.health()
+		h.ServiceName = s.name
+		h.Checked = checked
+
+		health = append(health, h)
+	}
+
+	return health
+}
+
+// ServeHTTP proxies the request to the backend picked by the strategy. If no
+// backend is available the circuit is open, and 503 Service Unavailable is
+// returned right away.
+func (s *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, cookie, retryAt := s.selectBackend(r)
+	if b == nil {
+		if !retryAt.IsZero() {
+			seconds := int(math.Ceil(retryAt.Sub(time.Now()).Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	defer b.release()
+
+	if cookie != nil {
+		http.SetCookie(w, cookie)
+	}
+
+	b.proxy.ServeHTTP(w, r)
+}
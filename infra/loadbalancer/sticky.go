@@ -0,0 +1,83 @@
+package loadbalancer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// DefaultStickyCookieName is the name of the affinity cookie if none is set
+// in the options.
+const DefaultStickyCookieName = "proxy_affinity"
+
+// keySize is the size of the generated signing key.
+const keySize = 32
+
+// sticky issues and verifies affinity cookies for a service. The cookie
+// value is an opaque backend ID: an HMAC over the service name and the
+// backend URL. It does not reveal the backend URL, cannot be forged, and is
+// not valid for another service.
+type sticky struct {
+	service string
+	key     []byte
+	options interfaces.StickySessions
+}
+
+func newSticky(service string, key []byte, options *interfaces.StickySessions) *sticky {
+	s := &sticky{
+		service: service,
+		key:     key,
+		options: *options,
+	}
+
+	if s.options.CookieName == "" {
+		s.options.CookieName = DefaultStickyCookieName
+	}
+
+	return s
+}
+
+// cookieID returns the backend ID from the affinity cookie of the request,
+// or false if it has no well-formed affinity cookie.
+func (s *sticky) cookieID(r *http.Request) ([]byte, bool) {
+	c, err := r.Cookie(s.options.CookieName)
+	if err != nil {
+		return nil, false
+	}
+
+	id, err := base64.RawURLEncoding.DecodeString(c.Value)
+	if err != nil || len(id) != sha256.Size {
+		return nil, false
+	}
+
+	return id, true
+}
+
+// matches returns true if id is the backend ID of b.
+func (s *sticky) matches(id []byte, b *backend) bool {
+	return hmac.Equal(id, s.backendID(b))
+}
+
+// cookie returns the affinity cookie for the backend.
+func (s *sticky) cookie(r *http.Request, b *backend) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.options.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(s.backendID(b)),
+		Path:     "/",
+		MaxAge:   int(s.options.TTL.Seconds()),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+	}
+}
+
+func (s *sticky) backendID(b *backend) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(s.service))
+	mac.Write([]byte{0})
+	mac.Write([]byte(b.url.String()))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,164 @@
+package loadbalancer
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+var stickyOptions = &interfaces.ServiceOptions{
+	StickySessions: &interfaces.StickySessions{TTL: time.Hour},
+}
+
+// getWithCookie performs a request with the optional cookie, and returns the
+// response body and the affinity cookie that was set, if any.
+func getWithCookie(t *testing.T, h http.Handler, cookie *http.Cookie) (string, *http.Cookie) {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	for _, c := range (&http.Response{Header: rec.Header()}).Cookies() {
+		if c.Name == DefaultStickyCookieName {
+			return rec.Body.String(), c
+		}
+	}
+
+	return rec.Body.String(), nil
+}
+
+func TestNewLoadBalancerWithKeyShouldReturnErrorOnMissingKey(t *testing.T) {
+	lb, err := NewLoadBalancerWithKey(logger, nil)
+
+	assert.Nil(t, lb)
+	assert.Equal(t, ErrMissingKey, err)
+}
+
+func TestStickySessionsShouldHonourAffinityCookie(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	first, cookie := getWithCookie(t, h, nil)
+	assert.NotNil(t, cookie)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+
+	// the cookie does not reveal the backend URL
+	assert.NotContains(t, cookie.Value, base64.RawURLEncoding.EncodeToString([]byte(a.URL)))
+	assert.NotContains(t, cookie.Value, base64.RawURLEncoding.EncodeToString([]byte(b.URL)))
+
+	for i := 0; i < 4; i++ {
+		body, c := getWithCookie(t, h, cookie)
+		assert.Equal(t, first, body)
+
+		// the cookie is only set once
+		assert.Nil(t, c)
+	}
+}
+
+func TestStickySessionsShouldIgnoreInvalidAffinityCookie(t *testing.T) {
+	a := namedServer("a")
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	_, cookie := getWithCookie(t, h, nil)
+
+	// another load balancer has another key
+	other, err := NewLoadBalancerWithKey(logger, []byte("other"))
+	assert.Nil(t, err)
+
+	oh, err := other.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	for _, value := range []string{cookie.Value, "garbage", "a.b"} {
+		body, c := getWithCookie(t, oh, &http.Cookie{Name: DefaultStickyCookieName, Value: value})
+		assert.Equal(t, "a", body)
+		assert.NotNil(t, c, value)
+	}
+}
+
+func TestStickySessionsShouldFallBackWhenServerIsRemoved(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	first, cookie := getWithCookie(t, h, nil)
+
+	remaining := b.URL
+	if first == "b" {
+		remaining = a.URL
+	}
+
+	_, err = lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(remaining))
+	assert.Nil(t, err)
+
+	body, c := getWithCookie(t, h, cookie)
+	assert.NotEqual(t, first, body)
+	assert.NotNil(t, c)
+	assert.NotEqual(t, cookie.Value, c.Value)
+}
+
+func TestStickySessionsShouldFallBackWhenServerIsEjected(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	first, cookie := getWithCookie(t, h, nil)
+
+	for _, backend := range h.(*service).backends {
+		if backend.url.String() == a.URL && first == "a" || backend.url.String() == b.URL && first == "b" {
+			backend.ejectedUntil = time.Now().Add(time.Minute)
+		}
+	}
+
+	body, c := getWithCookie(t, h, cookie)
+	assert.NotEqual(t, first, body)
+	assert.NotNil(t, c)
+}
+
+func TestStickySessionsShouldBeScopedToService(t *testing.T) {
+	a := namedServer("a")
+	defer a.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	api, err := lb.UpsertServiceWithOptions("api", stickyOptions, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	web, err := lb.UpsertServiceWithOptions("web", stickyOptions, mustParse(a.URL))
+	assert.Nil(t, err)
+
+	_, cookie := getWithCookie(t, api, nil)
+
+	_, c := getWithCookie(t, web, cookie)
+	assert.NotNil(t, c)
+}
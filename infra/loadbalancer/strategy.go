@@ -0,0 +1,295 @@
+package loadbalancer
+
+import (
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// WeightParameter is the query parameter of a server URL holding the weight
+// of the server. It is removed before requests are proxied to the server.
+const WeightParameter = "weight"
+
+// ringReplicas is the number of points on the consistent hash ring for a
+// server with weight 1.
+const ringReplicas = 100
+
+// strategy selects the backend for a request. Its methods are called with
+// the mu of the service held: update with a write lock, pick with a read
+// lock.
+type strategy interface {
+	// update sets the backends to select from.
+	update(backends []*backend)
+
+	// pick returns the backend for the request among the backends for which
+	// available returns true, or nil if there are none.
+	pick(r *http.Request, available func(*backend) bool) *backend
+}
+
+// newStrategy creates the strategy with the provided name.
+func newStrategy(name interfaces.Strategy, hashPolicy *interfaces.HashPolicy) (strategy, error) {
+	switch name {
+	case "", interfaces.StrategyRoundRobin:
+		return &roundRobin{}, nil
+	case interfaces.StrategyLeastRequests:
+		return &leastRequests{}, nil
+	case interfaces.StrategyWeightedRoundRobin:
+		return &weightedRoundRobin{}, nil
+	case interfaces.StrategyConsistentHash:
+		if hashPolicy == nil {
+			hashPolicy = &interfaces.HashPolicy{}
+		}
+
+		return &consistentHash{policy: *hashPolicy}, nil
+	default:
+		return nil, ErrUnknownStrategy
+	}
+}
+
+// parseServer returns the server URL without the weight parameter, and the
+// weight of the server, which defaults to 1.
+func parseServer(u *url.URL) (*url.URL, int, error) {
+	query := u.Query()
+
+	raw, found := query[WeightParameter]
+	if !found {
+		return u, 1, nil
+	}
+
+	weight, err := strconv.Atoi(raw[0])
+	if err != nil || weight < 1 {
+		return nil, 0, ErrInvalidWeight
+	}
+
+	query.Del(WeightParameter)
+
+	target := *u
+	target.RawQuery = query.Encode()
+
+	return &target, weight, nil
+}
+
+// roundRobin selects the available backends in turn.
+type roundRobin struct {
+	backends []*backend
+	next     uint64
+}
+
+func (s *roundRobin) update(backends []*backend) {
+	s.backends = backends
+}
+
+func (s *roundRobin) pick(r *http.Request, available func(*backend) bool) *backend {
+	n := uint64(len(s.backends))
+	if n < 1 {
+		return nil
+	}
+
+	start := atomic.AddUint64(&s.next, 1) - 1
+
+	for i := uint64(0); i < n; i++ {
+		b := s.backends[(start+i)%n]
+
+		if available(b) {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// leastRequests selects the available backend with the fewest requests in
+// progress relative to its weight. Ties are broken in round-robin order.
+type leastRequests struct {
+	backends []*backend
+	next     uint64
+}
+
+func (s *leastRequests) update(backends []*backend) {
+	s.backends = backends
+}
+
+func (s *leastRequests) pick(r *http.Request, available func(*backend) bool) *backend {
+	n := uint64(len(s.backends))
+	if n < 1 {
+		return nil
+	}
+
+	start := atomic.AddUint64(&s.next, 1) - 1
+
+	var (
+		best            *backend
+		bestOutstanding int
+	)
+
+	for i := uint64(0); i < n; i++ {
+		b := s.backends[(start+i)%n]
+
+		if !available(b) {
+			continue
+		}
+
+		outstanding := b.outstanding()
+
+		// compare outstanding / weight without dividing
+		if best == nil || outstanding*best.weight < bestOutstanding*b.weight {
+			best = b
+			bestOutstanding = outstanding
+		}
+	}
+
+	return best
+}
+
+// weightedRoundRobin selects the available backends in turn, in proportion
+// to their weights. It uses the smooth weighted round-robin algorithm, which
+// spreads the selections of a backend evenly.
+type weightedRoundRobin struct {
+	backends []*backend
+
+	mu      sync.Mutex
+	current map[*backend]int
+}
+
+func (s *weightedRoundRobin) update(backends []*backend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.backends = backends
+	s.current = make(map[*backend]int, len(backends))
+}
+
+func (s *weightedRoundRobin) pick(r *http.Request, available func(*backend) bool) *backend {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		best  *backend
+		total int
+	)
+
+	for _, b := range s.backends {
+		if !available(b) {
+			continue
+		}
+
+		s.current[b] += b.weight
+		total += b.weight
+
+		if best == nil || s.current[b] > s.current[best] {
+			best = b
+		}
+	}
+
+	if best != nil {
+		s.current[best] -= total
+	}
+
+	return best
+}
+
+// consistentHash selects the backend from a hash ring, based on a hash of
+// the request. If the backend for a hash is not available, the next backend
+// on the ring is used.
+type consistentHash struct {
+	policy interfaces.HashPolicy
+	ring   []ringPoint
+}
+
+type ringPoint struct {
+	hash    uint32
+	backend *backend
+}
+
+func (s *consistentHash) update(backends []*backend) {
+	var ring []ringPoint
+
+	for _, b := range backends {
+		key := b.url.String() + "#"
+
+		for i := 0; i < ringReplicas*b.weight; i++ {
+			ring = append(ring, ringPoint{
+				hash:    hash(key + strconv.Itoa(i)),
+				backend: b,
+			})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	s.ring = ring
+}
+
+func (s *consistentHash) pick(r *http.Request, available func(*backend) bool) *backend {
+	n := len(s.ring)
+	if n < 1 {
+		return nil
+	}
+
+	h := hash(s.key(r))
+
+	start := sort.Search(n, func(i int) bool {
+		return s.ring[i].hash >= h
+	})
+
+	checked := make(map[*backend]bool)
+
+	for i := 0; i < n; i++ {
+		b := s.ring[(start+i)%n].backend
+
+		if checked[b] {
+			continue
+		}
+
+		if available(b) {
+			return b
+		}
+
+		checked[b] = true
+	}
+
+	return nil
+}
+
+// key returns the request property to hash on.
+func (s *consistentHash) key(r *http.Request) string {
+	if s.policy.Header != "" {
+		if v := r.Header.Get(s.policy.Header); v != "" {
+			return v
+		}
+	}
+
+	if s.policy.Cookie != "" {
+		if c, err := r.Cookie(s.policy.Cookie); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+
+	return clientIP(r)
+}
+
+// clientIP returns the IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return h.Sum32()
+}
@@ -0,0 +1,206 @@
+package loadbalancer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func testBackends(weights ...int) []*backend {
+	var backends []*backend
+
+	for i, weight := range weights {
+		b := newBackend(mustParse("http://10.0.0."+strconv.Itoa(i+1)+":8080"), nil, logger)
+		b.weight = weight
+
+		backends = append(backends, b)
+	}
+
+	return backends
+}
+
+func allAvailable(*backend) bool {
+	return true
+}
+
+func requestWithHeader(key, value string) *http.Request {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.Header.Set(key, value)
+
+	return r
+}
+
+func TestParseServer(t *testing.T) {
+	u, weight, err := parseServer(mustParse("http://10.0.0.1:8080/api?weight=3&region=eu"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, weight)
+	assert.Equal(t, "http://10.0.0.1:8080/api?region=eu", u.String())
+
+	u, weight, err = parseServer(mustParse("http://10.0.0.1:8080"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, weight)
+	assert.Equal(t, "http://10.0.0.1:8080", u.String())
+
+	for _, raw := range []string{"0", "-1", "heavy", ""} {
+		_, _, err = parseServer(mustParse("http://10.0.0.1:8080?weight=" + raw))
+		assert.Equal(t, ErrInvalidWeight, err, raw)
+	}
+}
+
+func TestNewStrategyShouldReturnErrorOnUnknownStrategy(t *testing.T) {
+	st, err := newStrategy("random", nil)
+
+	assert.Nil(t, st)
+	assert.Equal(t, ErrUnknownStrategy, err)
+}
+
+func TestUpsertServiceWithOptionsShouldReturnErrorOnInvalidStrategyOrWeight(t *testing.T) {
+	lb := NewLoadBalancer(logger)
+
+	_, err := lb.UpsertServiceWithOptions("api", &interfaces.ServiceOptions{Strategy: "random"},
+		mustParse("http://10.0.0.1:8080"))
+	assert.Equal(t, ErrUnknownStrategy, err)
+
+	_, err = lb.UpsertService("api", mustParse("http://10.0.0.1:8080?weight=0"))
+	assert.Equal(t, ErrInvalidWeight, err)
+}
+
+func TestRoundRobinShouldSkipUnavailableBackends(t *testing.T) {
+	backends := testBackends(1, 1, 1)
+
+	s := &roundRobin{}
+	s.update(backends)
+
+	for i := 0; i < 6; i++ {
+		b := s.pick(nil, func(b *backend) bool { return b != backends[1] })
+		assert.NotEqual(t, backends[1], b)
+	}
+
+	assert.Nil(t, s.pick(nil, func(*backend) bool { return false }))
+}
+
+func TestLeastRequestsShouldPickBackendWithFewestRequests(t *testing.T) {
+	backends := testBackends(1, 1, 2)
+
+	backends[0].active = 2
+	backends[1].active = 1
+	backends[2].active = 3
+
+	s := &leastRequests{}
+	s.update(backends)
+
+	// 3 requests on a backend with weight 2 count as 1.5
+	assert.True(t, backends[1] == s.pick(nil, allAvailable))
+
+	backends[1].active = 2
+	assert.True(t, backends[2] == s.pick(nil, allAvailable))
+
+	assert.True(t, backends[0] == s.pick(nil, func(b *backend) bool { return b == backends[0] }))
+}
+
+func TestWeightedRoundRobinShouldSpreadSelectionsByWeight(t *testing.T) {
+	backends := testBackends(5, 1, 1)
+
+	s := &weightedRoundRobin{}
+	s.update(backends)
+
+	var picks []int
+
+	for i := 0; i < 7; i++ {
+		b := s.pick(nil, allAvailable)
+
+		for j := range backends {
+			if b == backends[j] {
+				picks = append(picks, j)
+			}
+		}
+	}
+
+	// the smooth algorithm interleaves the light backends
+	assert.Equal(t, []int{0, 0, 1, 0, 2, 0, 0}, picks)
+}
+
+func TestConsistentHashShouldPickSameBackendForSameKey(t *testing.T) {
+	backends := testBackends(1, 1, 1)
+
+	s := &consistentHash{policy: interfaces.HashPolicy{Header: "X-User"}}
+	s.update(backends)
+
+	picked := make(map[string]*backend)
+	used := make(map[*backend]bool)
+
+	for i := 0; i < 100; i++ {
+		user := strconv.Itoa(i)
+
+		b := s.pick(requestWithHeader("X-User", user), allAvailable)
+		assert.True(t, b == s.pick(requestWithHeader("X-User", user), allAvailable))
+
+		picked[user] = b
+		used[b] = true
+	}
+
+	assert.Len(t, used, 3)
+
+	// only the keys of an unavailable backend move
+	for user, b := range picked {
+		moved := s.pick(requestWithHeader("X-User", user), func(b *backend) bool { return b != backends[0] })
+
+		if b == backends[0] {
+			assert.False(t, moved == backends[0])
+		} else {
+			assert.True(t, moved == b)
+		}
+	}
+}
+
+func TestConsistentHashKey(t *testing.T) {
+	s := &consistentHash{policy: interfaces.HashPolicy{Cookie: "session"}}
+
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	assert.Equal(t, "192.0.2.1", s.key(r))
+
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	assert.Equal(t, "abc", s.key(r))
+}
+
+func TestUpsertServiceWithOptionsShouldUseWeights(t *testing.T) {
+	a, b := namedServer("a"), namedServer("b")
+	defer a.Close()
+	defer b.Close()
+
+	lb := NewLoadBalancer(logger)
+
+	h, err := lb.UpsertServiceWithOptions("api",
+		&interfaces.ServiceOptions{Strategy: interfaces.StrategyWeightedRoundRobin},
+		mustParse(a.URL+"?weight=3"), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		_, body := get(t, h)
+		counts[body]++
+	}
+
+	assert.Equal(t, map[string]int{"a": 6, "b": 2}, counts)
+
+	// changing the weight updates the backend in place
+	_, err = lb.UpsertServiceWithOptions("api",
+		&interfaces.ServiceOptions{Strategy: interfaces.StrategyWeightedRoundRobin},
+		mustParse(a.URL), mustParse(b.URL))
+	assert.Nil(t, err)
+
+	health := lb.BackendHealth()
+	assert.Len(t, health, 2)
+	assert.Equal(t, a.URL, health[0].URL.String())
+	assert.Equal(t, 1, h.(*service).backends[0].weight)
+}
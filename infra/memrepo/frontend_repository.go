@@ -0,0 +1,142 @@
+package memrepo
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/infra/broadcast"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// FrontendRepository is an in-memory interfaces.FrontendWatcher.
+type FrontendRepository struct {
+	mu        sync.RWMutex
+	frontends map[string]*frontends.Frontend
+
+	// revisions holds the last revision per frontend, assigned while holding mu
+	revisions map[string]uint64
+
+	events broadcast.FrontendEvents
+}
+
+// NewFrontendRepository creates a new FrontendRepository containing the
+// provided frontends.
+func NewFrontendRepository(initial ...*frontends.Frontend) (*FrontendRepository, error) {
+	r := &FrontendRepository{
+		frontends: make(map[string]*frontends.Frontend),
+		revisions: make(map[string]uint64),
+	}
+
+	for _, frontend := range initial {
+		if frontend == nil {
+			return nil, ErrMissingFrontend
+		}
+
+		r.frontends[frontend.Name] = frontend
+	}
+
+	return r, nil
+}
+
+// ListFrontends returns all frontend names contained in this repository,
+// sorted.
+func (r *FrontendRepository) ListFrontends() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.frontends))
+	for name := range r.frontends {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DescribeFrontend returns the frontend with the specified name. If no frontend
+// exists with that name an interfaces.ErrUnknownFrontend is returned.
+func (r *FrontendRepository) DescribeFrontend(name string) (*frontends.Frontend, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	frontend, found := r.frontends[name]
+	if !found {
+		return nil, interfaces.ErrUnknownFrontend
+	}
+
+	return frontend, nil
+}
+
+// Subscribe returns a channel through which frontend events will be
+// distributed.
+func (r *FrontendRepository) Subscribe() <-chan interfaces.FrontendEvent {
+	return r.events.Subscribe()
+}
+
+// Unsubscribe stops distributing events to a channel returned by Subscribe.
+func (r *FrontendRepository) Unsubscribe(events <-chan interfaces.FrontendEvent) {
+	r.events.Unsubscribe(events)
+}
+
+// Put creates or updates a frontend and publishes the corresponding event.
+func (r *FrontendRepository) Put(frontend *frontends.Frontend) error {
+	if frontend == nil {
+		return ErrMissingFrontend
+	}
+
+	r.mu.Lock()
+
+	kind := interfaces.EventCreated
+	if _, found := r.frontends[frontend.Name]; found {
+		kind = interfaces.EventUpdated
+	}
+
+	r.frontends[frontend.Name] = frontend
+	revision := r.nextRevision(frontend.Name)
+
+	r.mu.Unlock()
+
+	// publish outside the lock, so subscribers are able to describe; the
+	// revision orders events published concurrently
+	r.events.Publish(interfaces.FrontendEvent{
+		Name:     frontend.Name,
+		Kind:     kind,
+		Revision: revision,
+		Frontend: frontend,
+	})
+
+	return nil
+}
+
+// Delete deletes a frontend and publishes the corresponding event. Deleting
+// an unknown frontend does nothing.
+func (r *FrontendRepository) Delete(name string) {
+	r.mu.Lock()
+
+	if _, found := r.frontends[name]; !found {
+		r.mu.Unlock()
+
+		return
+	}
+
+	delete(r.frontends, name)
+	revision := r.nextRevision(name)
+
+	r.mu.Unlock()
+
+	r.events.Publish(interfaces.FrontendEvent{
+		Name:     name,
+		Kind:     interfaces.EventDeleted,
+		Revision: revision,
+	})
+}
+
+// nextRevision returns the next revision for the frontend. It must be called
+// while holding mu.
+func (r *FrontendRepository) nextRevision(name string) uint64 {
+	r.revisions[name]++
+
+	return r.revisions[name]
+}
@@ -0,0 +1,158 @@
+package memrepo
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+func mockFrontend(name string) *frontends.Frontend {
+	f, err := frontends.NewFrontend(name, "http://"+name, nil, name)
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return f
+}
+
+func TestNewFrontendRepository(t *testing.T) {
+	r, err := NewFrontendRepository(mockFrontend("b"), mockFrontend("a"))
+
+	assert.Nil(t, err)
+
+	names, err := r.ListFrontends()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestNewFrontendRepositoryShouldReturnErrorOnMissingFrontend(t *testing.T) {
+	r, err := NewFrontendRepository(nil)
+
+	assert.Nil(t, r)
+	assert.Equal(t, ErrMissingFrontend, err)
+}
+
+func TestFrontendRepositoryImplementsFrontendWatcher(t *testing.T) {
+	var _ interfaces.FrontendWatcher = &FrontendRepository{}
+}
+
+func TestDescribeFrontendShouldReturnErrorOnUnknownFrontend(t *testing.T) {
+	r, _ := NewFrontendRepository()
+
+	s, err := r.DescribeFrontend("unknown")
+
+	assert.Nil(t, s)
+	assert.Equal(t, interfaces.ErrUnknownFrontend, err)
+}
+
+func TestPutFrontendShouldReturnErrorOnMissingFrontend(t *testing.T) {
+	r, _ := NewFrontendRepository()
+
+	assert.Equal(t, ErrMissingFrontend, r.Put(nil))
+}
+
+func TestPutAndDeleteFrontendShouldPublishToAllSubscribers(t *testing.T) {
+	r, _ := NewFrontendRepository()
+
+	s1 := r.Subscribe()
+	s2 := r.Subscribe()
+
+	assert.Nil(t, r.Put(mockFrontend("testapp")))
+	assert.Nil(t, r.Put(mockFrontend("testapp")))
+	r.Delete("testapp")
+
+	// deleting an unknown frontend does not publish an event
+	r.Delete("unknown")
+
+	for _, events := range []<-chan interfaces.FrontendEvent{s1, s2} {
+		assert.Len(t, events, 3)
+
+		e := <-events
+		assert.Equal(t, interfaces.EventCreated, e.Kind)
+		assert.Equal(t, uint64(1), e.Revision)
+		assert.Equal(t, "testapp", e.Frontend.Name)
+
+		e = <-events
+		assert.Equal(t, interfaces.EventUpdated, e.Kind)
+		assert.Equal(t, uint64(2), e.Revision)
+
+		e = <-events
+		assert.Equal(t, interfaces.EventDeleted, e.Kind)
+		assert.Equal(t, uint64(3), e.Revision)
+		assert.Nil(t, e.Frontend)
+	}
+
+	_, err := r.DescribeFrontend("testapp")
+	assert.Equal(t, interfaces.ErrUnknownFrontend, err)
+}
+
+func TestFrontendRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	r, _ := NewFrontendRepository()
+
+	events := r.Subscribe()
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			r.Put(mockFrontend("testapp"))
+			r.ListFrontends()
+			r.DescribeFrontend("testapp")
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Len(t, events, 10)
+}
+
+func TestFrontendRepositoryRevisionsShouldFollowConcurrentChanges(t *testing.T) {
+	r, _ := NewFrontendRepository()
+
+	events := r.Subscribe()
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			r.Put(mockFrontend("testapp"))
+
+			if i%2 == 0 {
+				r.Delete("testapp")
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// the event with the last revision must match the final state
+	var last interfaces.FrontendEvent
+
+	for len(events) > 0 {
+		e := <-events
+		if e.Revision > last.Revision {
+			last = e
+		}
+	}
+
+	f, err := r.DescribeFrontend("testapp")
+	if err == nil {
+		assert.NotEqual(t, interfaces.EventDeleted, last.Kind)
+		assert.True(t, f == last.Frontend)
+	} else {
+		assert.Equal(t, interfaces.EventDeleted, last.Kind)
+	}
+}
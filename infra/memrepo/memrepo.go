@@ -0,0 +1,14 @@
+// Package memrepo provides concurrency-safe, in-memory service and frontend
+// repositories. They implement interfaces.ServiceWatcher and
+// interfaces.FrontendWatcher, publishing an event to all subscribers on
+// every change, which makes them suitable for embedding the proxy and as
+// reference implementations in tests.
+package memrepo
+
+import "errors"
+
+// Errors
+var (
+	ErrMissingService  = errors.New("missing service")
+	ErrMissingFrontend = errors.New("missing frontend")
+)
@@ -0,0 +1,142 @@
+package memrepo
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/infra/broadcast"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// ServiceRepository is an in-memory interfaces.ServiceWatcher.
+type ServiceRepository struct {
+	mu       sync.RWMutex
+	services map[string]*services.Service
+
+	// revisions holds the last revision per service, assigned while holding mu
+	revisions map[string]uint64
+
+	events broadcast.ServiceEvents
+}
+
+// NewServiceRepository creates a new ServiceRepository containing the
+// provided services.
+func NewServiceRepository(initial ...*services.Service) (*ServiceRepository, error) {
+	r := &ServiceRepository{
+		services:  make(map[string]*services.Service),
+		revisions: make(map[string]uint64),
+	}
+
+	for _, service := range initial {
+		if service == nil {
+			return nil, ErrMissingService
+		}
+
+		r.services[service.Name] = service
+	}
+
+	return r, nil
+}
+
+// ListServices returns all service names contained in this repository,
+// sorted.
+func (r *ServiceRepository) ListServices() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.services))
+	for name := range r.services {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DescribeService returns the service with the specified name. If no service
+// exists with that name an interfaces.ErrUnknownService is returned.
+func (r *ServiceRepository) DescribeService(name string) (*services.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	service, found := r.services[name]
+	if !found {
+		return nil, interfaces.ErrUnknownService
+	}
+
+	return service, nil
+}
+
+// Subscribe returns a channel through which service events will be
+// distributed.
+func (r *ServiceRepository) Subscribe() <-chan interfaces.ServiceEvent {
+	return r.events.Subscribe()
+}
+
+// Unsubscribe stops distributing events to a channel returned by Subscribe.
+func (r *ServiceRepository) Unsubscribe(events <-chan interfaces.ServiceEvent) {
+	r.events.Unsubscribe(events)
+}
+
+// Put creates or updates a service and publishes the corresponding event.
+func (r *ServiceRepository) Put(service *services.Service) error {
+	if service == nil {
+		return ErrMissingService
+	}
+
+	r.mu.Lock()
+
+	kind := interfaces.EventCreated
+	if _, found := r.services[service.Name]; found {
+		kind = interfaces.EventUpdated
+	}
+
+	r.services[service.Name] = service
+	revision := r.nextRevision(service.Name)
+
+	r.mu.Unlock()
+
+	// publish outside the lock, so subscribers are able to describe; the
+	// revision orders events published concurrently
+	r.events.Publish(interfaces.ServiceEvent{
+		Name:     service.Name,
+		Kind:     kind,
+		Revision: revision,
+		Service:  service,
+	})
+
+	return nil
+}
+
+// Delete deletes a service and publishes the corresponding event. Deleting
+// an unknown service does nothing.
+func (r *ServiceRepository) Delete(name string) {
+	r.mu.Lock()
+
+	if _, found := r.services[name]; !found {
+		r.mu.Unlock()
+
+		return
+	}
+
+	delete(r.services, name)
+	revision := r.nextRevision(name)
+
+	r.mu.Unlock()
+
+	r.events.Publish(interfaces.ServiceEvent{
+		Name:     name,
+		Kind:     interfaces.EventDeleted,
+		Revision: revision,
+	})
+}
+
+// nextRevision returns the next revision for the service. It must be called
+// while holding mu.
+func (r *ServiceRepository) nextRevision(name string) uint64 {
+	r.revisions[name]++
+
+	return r.revisions[name]
+}
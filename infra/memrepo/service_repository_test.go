@@ -0,0 +1,158 @@
+package memrepo
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+func mockService(name string) *services.Service {
+	s, err := services.NewService(name, "http://127.0.0.1:8080")
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return s
+}
+
+func TestNewServiceRepository(t *testing.T) {
+	r, err := NewServiceRepository(mockService("b"), mockService("a"))
+
+	assert.Nil(t, err)
+
+	names, err := r.ListServices()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+}
+
+func TestNewServiceRepositoryShouldReturnErrorOnMissingService(t *testing.T) {
+	r, err := NewServiceRepository(nil)
+
+	assert.Nil(t, r)
+	assert.Equal(t, ErrMissingService, err)
+}
+
+func TestServiceRepositoryImplementsServiceWatcher(t *testing.T) {
+	var _ interfaces.ServiceWatcher = &ServiceRepository{}
+}
+
+func TestDescribeServiceShouldReturnErrorOnUnknownService(t *testing.T) {
+	r, _ := NewServiceRepository()
+
+	s, err := r.DescribeService("unknown")
+
+	assert.Nil(t, s)
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+}
+
+func TestPutServiceShouldReturnErrorOnMissingService(t *testing.T) {
+	r, _ := NewServiceRepository()
+
+	assert.Equal(t, ErrMissingService, r.Put(nil))
+}
+
+func TestPutAndDeleteServiceShouldPublishToAllSubscribers(t *testing.T) {
+	r, _ := NewServiceRepository()
+
+	s1 := r.Subscribe()
+	s2 := r.Subscribe()
+
+	assert.Nil(t, r.Put(mockService("testapp")))
+	assert.Nil(t, r.Put(mockService("testapp")))
+	r.Delete("testapp")
+
+	// deleting an unknown service does not publish an event
+	r.Delete("unknown")
+
+	for _, events := range []<-chan interfaces.ServiceEvent{s1, s2} {
+		assert.Len(t, events, 3)
+
+		e := <-events
+		assert.Equal(t, interfaces.EventCreated, e.Kind)
+		assert.Equal(t, uint64(1), e.Revision)
+		assert.Equal(t, "testapp", e.Service.Name)
+
+		e = <-events
+		assert.Equal(t, interfaces.EventUpdated, e.Kind)
+		assert.Equal(t, uint64(2), e.Revision)
+
+		e = <-events
+		assert.Equal(t, interfaces.EventDeleted, e.Kind)
+		assert.Equal(t, uint64(3), e.Revision)
+		assert.Nil(t, e.Service)
+	}
+
+	_, err := r.DescribeService("testapp")
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+}
+
+func TestServiceRepositoryIsSafeForConcurrentUse(t *testing.T) {
+	r, _ := NewServiceRepository()
+
+	events := r.Subscribe()
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			r.Put(mockService("testapp"))
+			r.ListServices()
+			r.DescribeService("testapp")
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Len(t, events, 10)
+}
+
+func TestServiceRepositoryRevisionsShouldFollowConcurrentChanges(t *testing.T) {
+	r, _ := NewServiceRepository()
+
+	events := r.Subscribe()
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			r.Put(mockService("testapp"))
+
+			if i%2 == 0 {
+				r.Delete("testapp")
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	// the event with the last revision must match the final state
+	var last interfaces.ServiceEvent
+
+	for len(events) > 0 {
+		e := <-events
+		if e.Revision > last.Revision {
+			last = e
+		}
+	}
+
+	s, err := r.DescribeService("testapp")
+	if err == nil {
+		assert.NotEqual(t, interfaces.EventDeleted, last.Kind)
+		assert.True(t, s == last.Service)
+	} else {
+		assert.Equal(t, interfaces.EventDeleted, last.Kind)
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingCertificateExpiryReporter = errors.New("missing certificate expiry reporter")
+)
+
+// CertificateExpiryHandler exposes the expiry of the certificates serving the
+// hosts of a proxy in the Prometheus text format:
+//
+//	proxy_certificate_days_to_expiry{host="api.example.com"} 12.50
+//	proxy_certificate_expiry_timestamp_seconds{host="api.example.com"} 1500129600
+type CertificateExpiryHandler struct {
+	reporter interfaces.CertificateExpiryReporter
+	now      func() time.Time
+}
+
+// NewCertificateExpiryHandler creates a new handler for the expiries
+// provided by the reporter.
+func NewCertificateExpiryHandler(reporter interfaces.CertificateExpiryReporter) (*CertificateExpiryHandler, error) {
+	if reporter == nil {
+		return nil, ErrMissingCertificateExpiryReporter
+	}
+
+	return &CertificateExpiryHandler{
+		reporter: reporter,
+		now:      time.Now,
+	}, nil
+}
+
+func (h *CertificateExpiryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	expiries := h.reporter.CertificateExpiries()
+	now := h.now()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP proxy_certificate_days_to_expiry Days until the certificate serving the host expires.")
+	fmt.Fprintln(w, "# TYPE proxy_certificate_days_to_expiry gauge")
+
+	for _, expiry := range expiries {
+		days := expiry.NotAfter.Sub(now).Hours() / 24
+
+		fmt.Fprintf(w, "proxy_certificate_days_to_expiry{host=%s} %s\n",
+			quote(expiry.Host), formatFloat(days))
+	}
+
+	fmt.Fprintln(w, "# HELP proxy_certificate_expiry_timestamp_seconds Expiry of the certificate serving the host, in seconds since the epoch.")
+	fmt.Fprintln(w, "# TYPE proxy_certificate_expiry_timestamp_seconds gauge")
+
+	for _, expiry := range expiries {
+		fmt.Fprintf(w, "proxy_certificate_expiry_timestamp_seconds{host=%s} %d\n",
+			quote(expiry.Host), expiry.NotAfter.Unix())
+	}
+}
+
+// quote quotes a label value, escaping backslashes, quotes and newlines.
+func quote(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+
+	return `"` + value + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
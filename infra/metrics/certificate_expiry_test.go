@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+type dummyReporter struct {
+	expiries []*interfaces.CertificateExpiry
+}
+
+func (r *dummyReporter) CertificateExpiries() []*interfaces.CertificateExpiry {
+	return r.expiries
+}
+
+func TestNewCertificateExpiryHandlerShouldReturnErrorOnMissingReporter(t *testing.T) {
+	h, err := NewCertificateExpiryHandler(nil)
+
+	assert.Nil(t, h)
+	assert.Equal(t, ErrMissingCertificateExpiryReporter, err)
+}
+
+func TestCertificateExpiryHandler(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+
+	h, _ := NewCertificateExpiryHandler(&dummyReporter{
+		expiries: []*interfaces.CertificateExpiry{
+			{Host: "api.example.com", NotAfter: now.Add(36 * time.Hour)},
+			{Host: `odd"host`, NotAfter: now.Add(-12 * time.Hour)},
+		},
+	})
+	h.now = func() time.Time { return now }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `# HELP proxy_certificate_days_to_expiry Days until the certificate serving the host expires.
+# TYPE proxy_certificate_days_to_expiry gauge
+proxy_certificate_days_to_expiry{host="api.example.com"} 1.50
+proxy_certificate_days_to_expiry{host="odd\"host"} -0.50
+# HELP proxy_certificate_expiry_timestamp_seconds Expiry of the certificate serving the host, in seconds since the epoch.
+# TYPE proxy_certificate_expiry_timestamp_seconds gauge
+proxy_certificate_expiry_timestamp_seconds{host="api.example.com"} 1500129600
+proxy_certificate_expiry_timestamp_seconds{host="odd\"host"} 1499956800
+`, w.Body.String())
+}
@@ -0,0 +1,121 @@
+package webserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Errors
+var (
+	ErrMissingDomainName  = errors.New("missing domain name")
+	ErrMissingCertificate = errors.New("missing certificate")
+	ErrUnknownServerName  = errors.New("no certificate for server name")
+)
+
+// SecureWebServer extends WebServer with certificates per domain name, which
+// are selected using SNI. Use TLSConfig to configure the http.Server serving
+// it.
+type SecureWebServer struct {
+	*WebServer
+
+	certsMu sync.RWMutex
+	certs   map[string]*tls.Certificate
+}
+
+// NewSecureWebServer creates a new SecureWebServer without any routes or
+// certificates.
+func NewSecureWebServer() *SecureWebServer {
+	return &SecureWebServer{
+		WebServer: NewWebServer(),
+		certs:     make(map[string]*tls.Certificate),
+	}
+}
+
+// UpsertCertificate sets the certificate for the provided domain name. The
+// domain name may be a wildcard, such as *.example.com. The certificate and
+// private key must be PEM encoded.
+func (s *SecureWebServer) UpsertCertificate(domainName string, cert *frontends.Certificate) error {
+	if domainName == "" {
+		return ErrMissingDomainName
+	}
+
+	if cert == nil {
+		return ErrMissingCertificate
+	}
+
+	tlsCert, err := tls.X509KeyPair(cert.Certificate, cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	tlsCert.Leaf, err = x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	s.certsMu.Lock()
+	defer s.certsMu.Unlock()
+
+	s.certs[hostname(domainName)] = &tlsCert
+
+	return nil
+}
+
+// GetCertificate returns the certificate for the server name requested by
+// the client, falling back to a wildcard certificate for its parent domain.
+func (s *SecureWebServer) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(hello.ServerName)
+
+	s.certsMu.RLock()
+	defer s.certsMu.RUnlock()
+
+	if cert, found := s.certs[name]; found {
+		return cert, nil
+	}
+
+	if i := strings.Index(name, "."); i > 0 {
+		if cert, found := s.certs["*"+name[i:]]; found {
+			return cert, nil
+		}
+	}
+
+	return nil, ErrUnknownServerName
+}
+
+// InstalledCertificates returns the installed certificates, ordered by
+// domain name. It implements interfaces.CertificateInspector.
+func (s *SecureWebServer) InstalledCertificates() []*interfaces.InstalledCertificate {
+	s.certsMu.RLock()
+	defer s.certsMu.RUnlock()
+
+	certs := make([]*interfaces.InstalledCertificate, 0, len(s.certs))
+
+	for name, cert := range s.certs {
+		certs = append(certs, &interfaces.InstalledCertificate{
+			DomainName: name,
+			NotBefore:  cert.Leaf.NotBefore,
+			NotAfter:   cert.Leaf.NotAfter,
+		})
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].DomainName < certs[j].DomainName
+	})
+
+	return certs
+}
+
+// TLSConfig returns a TLS configuration selecting certificates using
+// GetCertificate.
+func (s *SecureWebServer) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: s.GetCertificate,
+	}
+}
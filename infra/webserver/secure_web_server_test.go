@@ -0,0 +1,146 @@
+package webserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+func TestSecureWebServerImplementsSecureWebServer(t *testing.T) {
+	var _ interfaces.SecureWebServer = NewSecureWebServer()
+}
+
+// selfSignedCertificate creates a PEM encoded self-signed certificate for the
+// provided DNS names.
+func selfSignedCertificate(t *testing.T, dnsNames ...string) *frontends.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &frontends.Certificate{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func TestUpsertCertificateShouldReturnErrorOnInvalidParameters(t *testing.T) {
+	s := NewSecureWebServer()
+
+	assert.Equal(t, ErrMissingDomainName, s.UpsertCertificate("", &frontends.Certificate{}))
+	assert.Equal(t, ErrMissingCertificate, s.UpsertCertificate("example.com", nil))
+	assert.NotNil(t, s.UpsertCertificate("example.com", &frontends.Certificate{}))
+}
+
+func TestGetCertificate(t *testing.T) {
+	s := NewSecureWebServer()
+
+	assert.Nil(t, s.UpsertCertificate("example.com", selfSignedCertificate(t, "example.com")))
+	assert.Nil(t, s.UpsertCertificate("*.example.com", selfSignedCertificate(t, "*.example.com")))
+
+	cert, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "Example.com"})
+	assert.Nil(t, err)
+	assert.NotNil(t, cert)
+
+	wildcard, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: "www.example.com"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, cert, wildcard)
+
+	_, err = s.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.com"})
+	assert.Equal(t, ErrUnknownServerName, err)
+}
+
+func TestInstalledCertificates(t *testing.T) {
+	s := NewSecureWebServer()
+
+	var _ interfaces.CertificateInspector = s
+
+	assert.Nil(t, s.UpsertCertificate("www.example.com:443", selfSignedCertificate(t, "www.example.com")))
+	assert.Nil(t, s.UpsertCertificate("*.example.com", selfSignedCertificate(t, "*.example.com")))
+
+	certs := s.InstalledCertificates()
+
+	if assert.Len(t, certs, 2) {
+		assert.Equal(t, "*.example.com", certs[0].DomainName)
+		assert.Equal(t, "www.example.com", certs[1].DomainName)
+		assert.True(t, certs[1].NotAfter.After(time.Now()))
+		assert.True(t, certs[1].NotBefore.Before(time.Now()))
+	}
+}
+
+func TestSecureWebServerShouldServeUsingSNI(t *testing.T) {
+	s := NewSecureWebServer()
+
+	cert := selfSignedCertificate(t, "example.com")
+
+	s.UpsertCertificate("example.com", cert)
+	s.UpsertRoute(mustParse("https://example.com/"), namedHandler("secure"))
+
+	server := httptest.NewUnstartedServer(s)
+	server.TLS = s.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(cert.Certificate)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				RootCAs:    roots,
+				ServerName: "example.com",
+			},
+		},
+	}
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Host = "example.com"
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	assert.Equal(t, "secure", string(body))
+}
@@ -0,0 +1,158 @@
+// Package webserver provides net/http based implementations of the
+// interfaces.WebServer and interfaces.SecureWebServer.
+package webserver
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Errors
+var (
+	ErrMissingRoute   = errors.New("missing route")
+	ErrMissingHandler = errors.New("missing handler")
+)
+
+// WebServer is an http.Handler that routes requests by host and longest path
+// prefix. Routes can be upserted and deleted while serving requests.
+// Requests not matching any route receive a 404 Not Found.
+type WebServer struct {
+	mu sync.RWMutex
+
+	// routes holds the routes per host name, ordered by descending prefix
+	// length
+	routes map[string][]*route
+}
+
+type route struct {
+	prefix  string
+	handler http.Handler
+}
+
+// NewWebServer creates a new WebServer without any routes.
+func NewWebServer() *WebServer {
+	return &WebServer{
+		routes: make(map[string][]*route),
+	}
+}
+
+// UpsertRoute adds a route to the web server, forwarding all requests for the
+// host of the route, with a path starting with the path of the route, to the
+// provided handler. The scheme and port of the route are ignored.
+func (s *WebServer) UpsertRoute(routeURL *url.URL, handler http.Handler) error {
+	if routeURL == nil {
+		return ErrMissingRoute
+	}
+
+	if handler == nil {
+		return ErrMissingHandler
+	}
+
+	host, prefix := routeKey(routeURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes := s.routes[host]
+
+	for _, r := range routes {
+		if r.prefix == prefix {
+			r.handler = handler
+
+			return nil
+		}
+	}
+
+	routes = append(routes, &route{prefix: prefix, handler: handler})
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].prefix) > len(routes[j].prefix)
+	})
+
+	s.routes[host] = routes
+
+	return nil
+}
+
+// DeleteRoute deletes a route from the web server.
+func (s *WebServer) DeleteRoute(routeURL *url.URL) {
+	if routeURL == nil {
+		return
+	}
+
+	host, prefix := routeKey(routeURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes := s.routes[host]
+
+	for i, r := range routes {
+		if r.prefix != prefix {
+			continue
+		}
+
+		routes = append(routes[:i:i], routes[i+1:]...)
+
+		break
+	}
+
+	if len(routes) == 0 {
+		delete(s.routes, host)
+	} else {
+		s.routes[host] = routes
+	}
+}
+
+// ServeHTTP forwards the request to the handler of the matching route.
+func (s *WebServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler := s.match(r.Host, r.URL.Path)
+	if handler == nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+func (s *WebServer) match(host, path string) http.Handler {
+	host = hostname(host)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.routes[host] {
+		if hasPathPrefix(path, r.prefix) {
+			return r.handler
+		}
+	}
+
+	return nil
+}
+
+// routeKey returns the host name and normalized path prefix of a route.
+func routeKey(routeURL *url.URL) (string, string) {
+	return strings.ToLower(routeURL.Hostname()), strings.TrimSuffix(routeURL.Path, "/")
+}
+
+// hasPathPrefix returns true if path equals prefix, or starts with prefix
+// followed by a slash. The empty prefix matches all paths.
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+// hostname returns the lower case host name without port.
+func hostname(host string) string {
+	u := url.URL{Host: host}
+
+	return strings.ToLower(u.Hostname())
+}
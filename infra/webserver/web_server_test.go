@@ -0,0 +1,138 @@
+package webserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestWebServerImplementsWebServer(t *testing.T) {
+	var _ interfaces.WebServer = NewWebServer()
+}
+
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, name)
+	})
+}
+
+func mustParse(rawurl string) *url.URL {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return u
+}
+
+func serve(h http.Handler, rawurl string) (int, string) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", rawurl, nil))
+
+	return w.Code, w.Body.String()
+}
+
+func TestUpsertRouteShouldReturnErrorOnMissingParameters(t *testing.T) {
+	s := NewWebServer()
+
+	assert.Equal(t, ErrMissingRoute, s.UpsertRoute(nil, namedHandler("a")))
+	assert.Equal(t, ErrMissingHandler, s.UpsertRoute(mustParse("http://a"), nil))
+}
+
+func TestServeHTTPShouldRouteByHostAndLongestPrefix(t *testing.T) {
+	s := NewWebServer()
+
+	s.UpsertRoute(mustParse("http://example.com"), namedHandler("root"))
+	s.UpsertRoute(mustParse("http://example.com/api"), namedHandler("api"))
+	s.UpsertRoute(mustParse("http://example.com/api/v2/"), namedHandler("api-v2"))
+	s.UpsertRoute(mustParse("http://other.com/"), namedHandler("other"))
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://example.com/", "root"},
+		{"http://example.com/apix", "root"},
+		{"http://example.com/api", "api"},
+		{"http://example.com/api/", "api"},
+		{"http://example.com/api/v1/users", "api"},
+		{"http://example.com/api/v2", "api-v2"},
+		{"http://example.com/api/v2/users", "api-v2"},
+		{"http://EXAMPLE.com:8080/api", "api"},
+		{"http://other.com/api", "other"},
+	}
+
+	for _, test := range tests {
+		code, body := serve(s, test.url)
+
+		assert.Equal(t, http.StatusOK, code, test.url)
+		assert.Equal(t, test.expected, body, test.url)
+	}
+
+	code, _ := serve(s, "http://unknown.com/")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestUpsertRouteShouldReplaceHandler(t *testing.T) {
+	s := NewWebServer()
+
+	s.UpsertRoute(mustParse("http://example.com/api"), namedHandler("old"))
+	s.UpsertRoute(mustParse("https://example.com/api/"), namedHandler("new"))
+
+	_, body := serve(s, "http://example.com/api")
+	assert.Equal(t, "new", body)
+}
+
+func TestDeleteRoute(t *testing.T) {
+	s := NewWebServer()
+
+	s.UpsertRoute(mustParse("http://example.com"), namedHandler("root"))
+	s.UpsertRoute(mustParse("http://example.com/api"), namedHandler("api"))
+
+	s.DeleteRoute(mustParse("http://example.com/api"))
+
+	_, body := serve(s, "http://example.com/api")
+	assert.Equal(t, "root", body)
+
+	s.DeleteRoute(mustParse("http://example.com"))
+	s.DeleteRoute(mustParse("http://unknown.com"))
+	s.DeleteRoute(nil)
+
+	code, _ := serve(s, "http://example.com/api")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestWebServerIsSafeForConcurrentUse(t *testing.T) {
+	s := NewWebServer()
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		route := mustParse(fmt.Sprintf("http://example.com/%d", i))
+
+		go func() {
+			defer wg.Done()
+
+			s.UpsertRoute(route, namedHandler(route.Path))
+			s.DeleteRoute(route)
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			serve(s, route.String())
+		}()
+	}
+
+	wg.Wait()
+}
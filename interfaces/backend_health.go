@@ -0,0 +1,43 @@
+package interfaces
+
+import (
+	"net/url"
+	"time"
+)
+
+// BackendHealth describes the health of a single server of a service.
+type BackendHealth struct {
+	ServiceName string
+	URL         *url.URL
+
+	// Healthy reports whether the server receives requests: it passes its
+	// health checks and is not ejected.
+	Healthy bool
+
+	// Checked reports whether the server is actively health checked.
+	Checked bool
+
+	// LastCheck is the time of the last health check, and LastError the
+	// reason it failed, if it did.
+	LastCheck time.Time
+	LastError error
+
+	ConsecutiveSuccesses int
+	ConsecutiveFailures  int
+
+	// Ejected reports whether the server is ejected by outlier detection
+	// until EjectedUntil. Ejections is the number of consecutive ejections,
+	// and ConsecutiveErrors the number of 5xx responses and connection
+	// errors since the last successful request.
+	Ejected           bool
+	EjectedUntil      time.Time
+	Ejections         int
+	ConsecutiveErrors int
+}
+
+// HealthReporter reports the health of the servers behind a load balancer.
+type HealthReporter interface {
+	// BackendHealth returns the health of all servers, ordered by service
+	// name.
+	BackendHealth() []*BackendHealth
+}
@@ -0,0 +1,26 @@
+package interfaces
+
+import "time"
+
+// CertificateExpiry describes when the certificate serving a host expires.
+type CertificateExpiry struct {
+	Host string
+
+	// DomainName is the domain name of the certificate, which differs from
+	// the host for wildcard certificates.
+	DomainName string
+
+	NotAfter time.Time
+
+	// DaysToExpiry is the number of whole days until the certificate
+	// expires, it is negative once the certificate has expired.
+	DaysToExpiry int
+
+	Expired bool
+}
+
+// CertificateExpiryReporter provides the expiry of the certificates serving
+// the hosts of a proxy.
+type CertificateExpiryReporter interface {
+	CertificateExpiries() []*CertificateExpiry
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import "net/http"
+
+// ACMEChallengePath is the path under which HTTP-01 challenges are served.
+const ACMEChallengePath = "/.well-known/acme-challenge/"
+
+// CertificateManager obtains and renews certificates for domain names
+// automatically, and installs them on a secure web server.
+type CertificateManager interface {
+	// ChallengeHandler returns the handler for HTTP-01 challenges. It must
+	// be routed for the ACMEChallengePath of each managed domain name on the
+	// plain web server.
+	ChallengeHandler() http.Handler
+
+	// ManageCertificate starts obtaining and renewing a certificate for the
+	// domain name. Calls are counted, each needs a matching call to
+	// UnmanageCertificate.
+	ManageCertificate(domainName string) error
+
+	// UnmanageCertificate stops renewing the certificate for the domain name
+	// once it is no longer managed for any caller.
+	UnmanageCertificate(domainName string)
+}
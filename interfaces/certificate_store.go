@@ -0,0 +1,40 @@
+package interfaces
+
+import (
+	"errors"
+	"time"
+
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Errors
+var (
+	ErrUnknownCertificate = errors.New("unknown certificate")
+)
+
+// StoredCertificate is a certificate kept by a certificate store, together
+// with its validity period.
+type StoredCertificate struct {
+	DomainName  string
+	Certificate *frontends.Certificate
+	NotBefore   time.Time
+	NotAfter    time.Time
+}
+
+// CertificateStore persists certificates by domain name, so they can be
+// installed again after a restart.
+type CertificateStore interface {
+	// ListCertificates returns all certificates in this store, ordered by
+	// domain name.
+	ListCertificates() ([]*StoredCertificate, error)
+
+	// DescribeCertificate returns the certificate for the specified domain
+	// name. If the store holds no certificate for that domain name an
+	// ErrUnknownCertificate is returned.
+	DescribeCertificate(domainName string) (*StoredCertificate, error)
+
+	// PutCertificate stores the certificate for the domain name, replacing
+	// the stored certificate, if any. The certificate and private key must be
+	// PEM encoded.
+	PutCertificate(domainName string, cert *frontends.Certificate) (*StoredCertificate, error)
+}
@@ -0,0 +1,28 @@
+package interfaces
+
+// EventKind specifies the kind of change that happened to a service or
+// frontend.
+type EventKind int
+
+// Event kinds. EventChanged is the zero value and is used by watchers that do
+// not know what kind of change happened; consumers should describe the entity
+// to find out.
+const (
+	EventChanged EventKind = iota
+	EventCreated
+	EventUpdated
+	EventDeleted
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventCreated:
+		return "created"
+	case EventUpdated:
+		return "updated"
+	case EventDeleted:
+		return "deleted"
+	default:
+		return "changed"
+	}
+}
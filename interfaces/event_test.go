@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventKindZeroValueIsChanged(t *testing.T) {
+	var k EventKind
+
+	assert.Equal(t, EventChanged, k)
+}
+
+func TestEventKindString(t *testing.T) {
+	assert.Equal(t, "changed", EventChanged.String())
+	assert.Equal(t, "created", EventCreated.String())
+	assert.Equal(t, "updated", EventUpdated.String())
+	assert.Equal(t, "deleted", EventDeleted.String())
+}
@@ -0,0 +1,99 @@
+package interfaces
+
+import "time"
+
+// FrontendOptions holds the options of a frontend that are not part of its
+// domain model.
+type FrontendOptions struct {
+	// AutoTLS requests a certificate to be obtained and renewed
+	// automatically for frontends without a certificate.
+	AutoTLS bool
+
+	// RedirectStatus is the status code used to redirect HTTP requests to
+	// HTTPS: 301, 302, 307 or 308. Defaults to 301.
+	RedirectStatus int
+
+	// SkipChallengeRedirect disables the redirect for ACME challenge paths,
+	// which are passed to the service instead. This allows services to
+	// obtain their certificates themselves.
+	SkipChallengeRedirect bool
+
+	// SecurityHeaders specifies the headers added to the HTTPS responses of
+	// the frontend. It is optional.
+	SecurityHeaders *SecurityHeaders
+
+	// Middlewares declares the middlewares applied to the requests of the
+	// frontend, in order: the first middleware handles a request first.
+	Middlewares []*MiddlewareConfig
+
+	// PathRewrite rewrites the path of the requests passed to the service.
+	// It is optional.
+	PathRewrite *PathRewrite
+}
+
+// PathRewrite specifies how a request path is rewritten. The steps are
+// applied in the order of the fields: strip, regex replace, add. A stripped
+// prefix is passed to the service in the X-Forwarded-Prefix header, after the
+// prefix forwarded upstream. If the regex or added prefix changes the path,
+// the original request URI is passed in the X-Forwarded-Uri header.
+type PathRewrite struct {
+	// StripPrefix is removed from the start of the path, e.g. /api, if the
+	// path equals it or continues with a slash. It must not be /.
+	StripPrefix string
+
+	// Regex is replaced by Replacement in the path, where Replacement can
+	// refer to submatches as in regexp.Regexp.ReplaceAllString.
+	Regex       string
+	Replacement string
+
+	// AddPrefix is prepended to the path.
+	AddPrefix string
+}
+
+// SecurityHeaders specifies a security header policy. Empty values do not
+// add a header.
+type SecurityHeaders struct {
+	// HSTS specifies the Strict-Transport-Security header.
+	HSTS *HSTS
+
+	// ContentTypeNosniff adds X-Content-Type-Options: nosniff.
+	ContentTypeNosniff bool
+
+	// FrameOptions is the value of the X-Frame-Options header, e.g. DENY.
+	FrameOptions string
+
+	// ContentSecurityPolicy is the value of the Content-Security-Policy
+	// header.
+	ContentSecurityPolicy string
+
+	// ReferrerPolicy is the value of the Referrer-Policy header.
+	ReferrerPolicy string
+
+	// Headers holds additional headers, keyed by name. An empty value removes
+	// the header from the response.
+	Headers map[string]string
+}
+
+// HSTS specifies the Strict-Transport-Security header.
+type HSTS struct {
+	// MaxAge is the time browsers only connect using HTTPS after receiving
+	// the header. It is rounded down to whole seconds.
+	MaxAge time.Duration
+
+	// IncludeSubDomains applies the policy to all subdomains of the host.
+	IncludeSubDomains bool
+
+	// Preload consents to including the host in the HSTS preload lists of
+	// browsers. It requires IncludeSubDomains and a MaxAge of at least a
+	// year.
+	Preload bool
+}
+
+// FrontendOptionsRepository is implemented by frontend repositories that
+// also provide options for their frontends.
+type FrontendOptionsRepository interface {
+	// DescribeFrontendOptions returns the options of the frontend with the
+	// specified name, or nil if it has none. If no frontend exists with that
+	// name an ErrUnknownFrontend is returned.
+	DescribeFrontendOptions(name string) (*FrontendOptions, error)
+}
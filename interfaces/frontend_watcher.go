@@ -1,9 +1,23 @@
 package interfaces
 
+import "github.com/off-sync/platform-proxy-domain/frontends"
+
 // FrontendEvent contains the information related to an event that happened
 // to a frontend.
 type FrontendEvent struct {
 	Name string
+
+	// Kind specifies what happened to the frontend.
+	Kind EventKind
+
+	// Revision is a monotonically increasing number per frontend that allows
+	// consumers to ignore out-of-order events. A zero revision means that the
+	// watcher does not track revisions.
+	Revision uint64
+
+	// Frontend optionally holds the new frontend configuration for created and
+	// updated events, which removes the need to describe the frontend.
+	Frontend *frontends.Frontend
 }
 
 // FrontendWatcher defines an interface for a frontend watcher against which
@@ -14,4 +28,9 @@ type FrontendWatcher interface {
 	// Subscribe returns a channel through which frontend events will
 	// be distributed.
 	Subscribe() <-chan FrontendEvent
+
+	// Unsubscribe stops the distribution of events to a channel returned
+	// by Subscribe and closes it. Subscribers must unsubscribe when they
+	// stop receiving, as the watcher may block on a full channel.
+	Unsubscribe(events <-chan FrontendEvent)
 }
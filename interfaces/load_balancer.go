@@ -15,3 +15,12 @@ type LoadBalancer interface {
 	// DeleteService deletes the service from the load balancer.
 	DeleteService(name string)
 }
+
+// ConfigurableLoadBalancer is a LoadBalancer that supports service options.
+type ConfigurableLoadBalancer interface {
+	LoadBalancer
+
+	// UpsertServiceWithOptions sets the urls and options for a service. A nil
+	// options value is the same as calling UpsertService.
+	UpsertServiceWithOptions(name string, options *ServiceOptions, urls ...*url.URL) (http.Handler, error)
+}
@@ -0,0 +1,57 @@
+package interfaces
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Errors
+var (
+	ErrUnknownMiddleware = errors.New("unknown middleware")
+)
+
+// Middleware adds cross-cutting behaviour, such as headers, authentication
+// or rate limiting, to the handler of a service.
+type Middleware interface {
+	// Wrap returns a handler applying the middleware, which calls next to
+	// continue handling the request.
+	Wrap(next http.Handler) http.Handler
+}
+
+// ReconfigurableMiddleware is implemented by middlewares with state that
+// should survive the reconfiguration of a frontend, such as rate limits.
+// Instead of creating a new middleware, the proxy reconfigures the middleware
+// with the same name that was used previously by the frontend. The options
+// are validated by creating a middleware first, and only applied once the
+// frontend was updated successfully, so that limits in use are not changed
+// by a failed update.
+type ReconfigurableMiddleware interface {
+	Middleware
+
+	// Reconfigure updates the options of the middleware in place. If an
+	// error is returned, the middleware is unchanged.
+	Reconfigure(options map[string]string) error
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface.
+type MiddlewareFunc func(next http.Handler) http.Handler
+
+// Wrap calls f(next).
+func (f MiddlewareFunc) Wrap(next http.Handler) http.Handler {
+	return f(next)
+}
+
+// MiddlewareConfig declares a middleware by the name it is registered under,
+// together with its options.
+type MiddlewareConfig struct {
+	Name    string
+	Options map[string]string
+}
+
+// MiddlewareRegistry creates middlewares by name.
+type MiddlewareRegistry interface {
+	// CreateMiddleware returns the middleware registered under the name of
+	// the config, configured using its options. If no middleware is
+	// registered with that name an ErrUnknownMiddleware is returned.
+	CreateMiddleware(config *MiddlewareConfig) (Middleware, error)
+}
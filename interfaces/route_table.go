@@ -0,0 +1,64 @@
+package interfaces
+
+import "net/url"
+
+// ServiceHandlerKind specifies which kind of handler serves the requests for
+// a route.
+type ServiceHandlerKind int
+
+// Service handler kinds.
+const (
+	// ServiceHandlerLoadBalancer is the handler returned by the load balancer.
+	ServiceHandlerLoadBalancer ServiceHandlerKind = iota
+
+	// ServiceHandlerNotFound is the fallback used when the service is not
+	// configured; it responds with 404 Not Found.
+	ServiceHandlerNotFound
+
+	// ServiceHandlerError is the fallback used when the service could not be
+	// configured on the load balancer; it responds with 500 Internal Server
+	// Error.
+	ServiceHandlerError
+)
+
+func (k ServiceHandlerKind) String() string {
+	switch k {
+	case ServiceHandlerNotFound:
+		return "not-found"
+	case ServiceHandlerError:
+		return "error"
+	default:
+		return "load-balancer"
+	}
+}
+
+// Route describes a route installed on one of the web servers.
+type Route struct {
+	FrontendName string
+	URL          *url.URL
+
+	// IsSecure is true for routes installed on the secure web server.
+	IsSecure bool
+
+	// IsRedirect is true for routes redirecting HTTP requests to HTTPS.
+	IsRedirect bool
+
+	// IsChallenge is true for routes serving ACME HTTP-01 challenges.
+	IsChallenge bool
+
+	// IsMaintenance is true for routes serving the maintenance handler, as
+	// the certificate of the frontend has expired.
+	IsMaintenance bool
+
+	ServiceName    string
+	ServiceHandler ServiceHandlerKind
+
+	// LastError is the last error encountered configuring the frontend or its
+	// service, or nil if the last configuration succeeded.
+	LastError error
+}
+
+// RouteTable provides the routes currently installed by a proxy.
+type RouteTable interface {
+	Routes() []*Route
+}
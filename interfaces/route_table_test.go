@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceHandlerKindZeroValueIsLoadBalancer(t *testing.T) {
+	var k ServiceHandlerKind
+
+	assert.Equal(t, ServiceHandlerLoadBalancer, k)
+}
+
+func TestServiceHandlerKindString(t *testing.T) {
+	assert.Equal(t, "load-balancer", ServiceHandlerLoadBalancer.String())
+	assert.Equal(t, "not-found", ServiceHandlerNotFound.String())
+	assert.Equal(t, "error", ServiceHandlerError.String())
+}
@@ -0,0 +1,115 @@
+package interfaces
+
+import "time"
+
+// Strategy names the way in which a load balancer selects the server for a
+// request.
+type Strategy string
+
+// Strategies
+const (
+	// StrategyRoundRobin selects the servers in turn. It is the default.
+	StrategyRoundRobin Strategy = "round-robin"
+
+	// StrategyLeastRequests selects the server with the fewest requests in
+	// progress.
+	StrategyLeastRequests Strategy = "least-requests"
+
+	// StrategyWeightedRoundRobin selects the servers in turn, in proportion
+	// to their weights.
+	StrategyWeightedRoundRobin Strategy = "weighted-round-robin"
+
+	// StrategyConsistentHash selects the server based on a hash of the
+	// request, see HashPolicy. Requests with the same hash go to the same
+	// server for as long as it is available.
+	StrategyConsistentHash Strategy = "consistent-hash"
+)
+
+// HashPolicy selects the request property that consistent hashing is based
+// on: a header, a cookie, or the client IP if neither is set. Requests
+// without the header or cookie are hashed on the client IP as well.
+type HashPolicy struct {
+	Header string
+	Cookie string
+}
+
+// StickySessions configures session affinity: the load balancer issues a
+// signed cookie naming the server that handled a request, and sends later
+// requests with that cookie to the same server while it is available.
+type StickySessions struct {
+	// CookieName is the name of the affinity cookie. The load balancer
+	// provides a default if empty.
+	CookieName string
+
+	// TTL is the maximum age of the affinity cookie. A zero TTL results in
+	// a session cookie.
+	TTL time.Duration
+}
+
+// HealthCheck configures the active health checking of the servers of a
+// service. Zero values are replaced by the defaults of the load balancer.
+type HealthCheck struct {
+	// Path is requested on each server, e.g. /healthz. Responses with a 2xx
+	// or 3xx status code are healthy.
+	Path string
+
+	// Interval is the time between two checks of a server.
+	Interval time.Duration
+
+	// Timeout is the time after which a check is considered failed.
+	Timeout time.Duration
+
+	// HealthyThreshold is the number of consecutive successful checks after
+	// which an ejected server is admitted again.
+	HealthyThreshold int
+
+	// UnhealthyThreshold is the number of consecutive failed checks after
+	// which a server is ejected.
+	UnhealthyThreshold int
+}
+
+// OutlierDetection configures the passive health checking of the servers of
+// a service, based on the responses to proxied requests. Zero values are
+// replaced by the defaults of the load balancer.
+type OutlierDetection struct {
+	// ConsecutiveErrors is the number of consecutive 5xx responses or
+	// connection errors after which a server is ejected.
+	ConsecutiveErrors int
+
+	// BaseEjectionTime is the time a server is ejected for the first time.
+	// It doubles with each consecutive ejection.
+	BaseEjectionTime time.Duration
+
+	// MaxEjectionTime limits the time a server is ejected.
+	MaxEjectionTime time.Duration
+}
+
+// ServiceOptions holds the load balancing options of a service. Server
+// weights are part of the server URLs, as the weight query parameter, e.g.
+// http://10.0.0.1:8080?weight=3.
+type ServiceOptions struct {
+	// Strategy selects the load balancing strategy, round-robin if empty.
+	Strategy Strategy
+
+	// HashPolicy is used by the consistent hash strategy.
+	HashPolicy *HashPolicy
+
+	// StickySessions enables session affinity if set. Requests without a
+	// valid affinity cookie are balanced using the strategy.
+	StickySessions *StickySessions
+
+	// HealthCheck enables active health checking if set.
+	HealthCheck *HealthCheck
+
+	// OutlierDetection enables passive health checking if set.
+	OutlierDetection *OutlierDetection
+}
+
+// ServiceOptionsRepository is implemented by service repositories that also
+// provide load balancing options for their services.
+type ServiceOptionsRepository interface {
+	// DescribeServiceOptions returns the options of the service with the
+	// specified name, or nil if it has none. If no service exists with that
+	// name an ErrUnknownService is returned.
+	DescribeServiceOptions(name string) (*ServiceOptions, error)
+}
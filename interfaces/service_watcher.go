@@ -1,9 +1,23 @@
 package interfaces
 
+import "github.com/off-sync/platform-proxy-domain/services"
+
 // ServiceEvent contains the information related to an event that happened
 // to a service.
 type ServiceEvent struct {
 	Name string
+
+	// Kind specifies what happened to the service.
+	Kind EventKind
+
+	// Revision is a monotonically increasing number per service that allows
+	// consumers to ignore out-of-order events. A zero revision means that the
+	// watcher does not track revisions.
+	Revision uint64
+
+	// Service optionally holds the new service configuration for created and
+	// updated events, which removes the need to describe the service.
+	Service *services.Service
 }
 
 // ServiceWatcher defines an interface for a service watcher against which
@@ -14,4 +28,9 @@ type ServiceWatcher interface {
 	// Subscribe returns a channel through which service events will
 	// be distributed.
 	Subscribe() <-chan ServiceEvent
+
+	// Unsubscribe stops the distribution of events to a channel returned
+	// by Subscribe and closes it. Subscribers must unsubscribe when they
+	// stop receiving, as the watcher may block on a full channel.
+	Unsubscribe(events <-chan ServiceEvent)
 }
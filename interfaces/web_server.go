@@ -3,6 +3,7 @@ package interfaces
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/off-sync/platform-proxy-domain/frontends"
 )
@@ -26,3 +27,19 @@ type SecureWebServer interface {
 	// UpsertCertificate sets the certificate for the provided domain name.
 	UpsertCertificate(domainName string, cert *frontends.Certificate) error
 }
+
+// InstalledCertificate describes a certificate installed on a secure web
+// server.
+type InstalledCertificate struct {
+	DomainName string
+	NotBefore  time.Time
+	NotAfter   time.Time
+}
+
+// CertificateInspector is implemented by secure web servers that are able to
+// describe the certificates installed on them.
+type CertificateInspector interface {
+	// InstalledCertificates returns the installed certificates, ordered by
+	// domain name.
+	InstalledCertificates() []*InstalledCertificate
+}
@@ -0,0 +1,285 @@
+package startproxy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// Certificate validation errors
+var (
+	ErrCertificateMissing      = errors.New("no certificate in PEM data")
+	ErrCertificateChainInvalid = errors.New("certificate chain is not signed in order")
+	ErrCertificateKeyMissing   = errors.New("no private key in PEM data")
+	ErrCertificateKeyInvalid   = errors.New("private key is invalid")
+	ErrCertificateKeyMismatch  = errors.New("private key does not match certificate")
+	ErrCertificateHostMismatch = errors.New("certificate does not cover host")
+	ErrCertificateExpired      = errors.New("certificate has expired")
+	ErrCertificateNotYetValid  = errors.New("certificate is not yet valid")
+)
+
+// CertificateError describes why the certificate of a frontend was not
+// installed. Err is one of the certificate validation errors, or the error
+// encountered parsing the certificate.
+type CertificateError struct {
+	Host string
+	Err  error
+}
+
+func (e *CertificateError) Error() string {
+	return "certificate for " + e.Host + ": " + e.Err.Error()
+}
+
+// installCertificate validates the certificate of the frontend and installs
+// it on the secure web server. An invalid certificate is not installed, so
+// the certificate installed previously for the host, if any, stays in use.
+func (p *proxy) installCertificate(frontend *frontends.Frontend) error {
+	// certificates are served by host name, without port
+	host := strings.ToLower(frontend.URL.Hostname())
+
+	err := validateCertificate(host, frontend.Certificate, time.Now())
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("host", host).
+			Error("validating certificate")
+
+		return err
+	}
+
+	err = p.secureWebServer.UpsertCertificate(host, frontend.Certificate)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("host", host).
+			Error("upserting certificate")
+
+		return err
+	}
+
+	p.storeCertificate(host, frontend.Certificate)
+
+	return nil
+}
+
+// restoreCertificates installs the unexpired certificates kept in the
+// certificate store on the secure web server. Only the certificates for the
+// hosts of the frontends are restored, so certificates of deleted frontends
+// are neither installed nor monitored.
+func (p *proxy) restoreCertificates() {
+	if p.certificateStore == nil {
+		return
+	}
+
+	certs, err := p.certificateStore.ListCertificates()
+	if err != nil {
+		p.logger.
+			WithError(err).
+			Error("listing stored certificates")
+
+		return
+	}
+
+	hosts, err := p.frontendHosts()
+	if err != nil {
+		p.logger.
+			WithError(err).
+			Error("listing frontend hosts")
+
+		return
+	}
+
+	now := time.Now()
+
+	for _, cert := range certs {
+		if !now.Before(cert.NotAfter) || !hosts[strings.ToLower(cert.DomainName)] {
+			continue
+		}
+
+		err := p.secureWebServer.UpsertCertificate(cert.DomainName, cert.Certificate)
+		if err != nil {
+			p.logger.
+				WithError(err).
+				WithField("domain_name", cert.DomainName).
+				Error("restoring certificate")
+
+			continue
+		}
+
+		p.logger.
+			WithField("domain_name", cert.DomainName).
+			WithField("not_after", cert.NotAfter).
+			Debug("restored certificate")
+	}
+}
+
+// frontendHosts returns the hosts of all frontends in the repository, both
+// with and without port, in lower case.
+func (p *proxy) frontendHosts() (map[string]bool, error) {
+	names, err := p.frontendRepository.ListFrontends()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make(map[string]bool)
+
+	for _, name := range names {
+		frontend, err := p.frontendRepository.DescribeFrontend(name)
+		if err != nil {
+			// the frontend is reported when it is configured
+			continue
+		}
+
+		hosts[strings.ToLower(frontend.URL.Host)] = true
+		hosts[strings.ToLower(frontend.URL.Hostname())] = true
+	}
+
+	return hosts, nil
+}
+
+// storeCertificate persists the certificate of a frontend, if a certificate
+// store is configured. Errors are logged, as the certificate is installed
+// already.
+func (p *proxy) storeCertificate(domainName string, cert *frontends.Certificate) {
+	if p.certificateStore == nil {
+		return
+	}
+
+	_, err := p.certificateStore.PutCertificate(domainName, cert)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("domain_name", domainName).
+			Error("storing certificate")
+	}
+}
+
+// validateCertificate checks that cert can be used to serve host at time now:
+// the certificate chain must parse and be signed in order, the private key
+// must match the leaf certificate, and the leaf certificate must cover host
+// and be valid at time now. It returns a *CertificateError otherwise.
+func validateCertificate(host string, cert *frontends.Certificate, now time.Time) error {
+	chain, err := parseChain(cert.Certificate)
+	if err != nil {
+		return &CertificateError{Host: host, Err: err}
+	}
+
+	for i := 0; i < len(chain)-1; i++ {
+		if chain[i].CheckSignatureFrom(chain[i+1]) != nil {
+			return &CertificateError{Host: host, Err: ErrCertificateChainInvalid}
+		}
+	}
+
+	key, err := parsePrivateKey(cert.PrivateKey)
+	if err != nil {
+		return &CertificateError{Host: host, Err: err}
+	}
+
+	leaf := chain[0]
+
+	if !publicKeyMatches(leaf.PublicKey, key) {
+		return &CertificateError{Host: host, Err: ErrCertificateKeyMismatch}
+	}
+
+	if leaf.VerifyHostname(host) != nil {
+		return &CertificateError{Host: host, Err: ErrCertificateHostMismatch}
+	}
+
+	if now.Before(leaf.NotBefore) {
+		return &CertificateError{Host: host, Err: ErrCertificateNotYetValid}
+	}
+
+	if now.After(leaf.NotAfter) {
+		return &CertificateError{Host: host, Err: ErrCertificateExpired}
+	}
+
+	return nil
+}
+
+// parsePrivateKey parses the first PEM encoded private key, in PKCS #1,
+// PKCS #8 or SEC 1 form.
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+
+		block, keyPEM = pem.Decode(keyPEM)
+		if block == nil {
+			return nil, ErrCertificateKeyMissing
+		}
+
+		if block.Type != "PRIVATE KEY" && !strings.HasSuffix(block.Type, " PRIVATE KEY") {
+			continue
+		}
+
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			if signer, ok := key.(crypto.Signer); ok {
+				return signer, nil
+			}
+		}
+
+		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+
+		return nil, ErrCertificateKeyInvalid
+	}
+}
+
+// publicKeyMatches returns true if key is the private key of the RSA or
+// ECDSA public key.
+func publicKeyMatches(public crypto.PublicKey, key crypto.Signer) bool {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		priv, ok := key.(*rsa.PrivateKey)
+
+		return ok && pub.N.Cmp(priv.N) == 0 && pub.E == priv.E
+	case *ecdsa.PublicKey:
+		priv, ok := key.(*ecdsa.PrivateKey)
+
+		return ok && pub.Curve == priv.Curve && pub.X.Cmp(priv.X) == 0 && pub.Y.Cmp(priv.Y) == 0
+	default:
+		return false
+	}
+}
+
+// parseChain parses the PEM encoded certificates, starting with the leaf.
+func parseChain(certPEM []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, ErrCertificateMissing
+	}
+
+	return chain, nil
+}
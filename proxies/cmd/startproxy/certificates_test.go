@@ -0,0 +1,177 @@
+package startproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+// testCertificates caches the certificates returned by validCertificate.
+var testCertificates = struct {
+	sync.Mutex
+	certs map[string]*frontends.Certificate
+}{certs: make(map[string]*frontends.Certificate)}
+
+// validCertificate returns a self-signed certificate for host, valid for a
+// day.
+func validCertificate(host string) *frontends.Certificate {
+	testCertificates.Lock()
+	defer testCertificates.Unlock()
+
+	cert, found := testCertificates.certs[host]
+	if !found {
+		cert = testCertificate(host, time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour))
+		testCertificates.certs[host] = cert
+	}
+
+	return cert
+}
+
+// testCertificate returns a PEM encoded self-signed certificate for host,
+// valid from notBefore until notAfter.
+func testCertificate(host string, notBefore, notAfter time.Time) *frontends.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return &frontends.Certificate{
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKey:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func assertCertificateError(t *testing.T, expected, err error) {
+	if certErr, ok := err.(*CertificateError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, expected, certErr.Err)
+	}
+}
+
+func TestValidateCertificate(t *testing.T) {
+	now := time.Now()
+
+	assert.Nil(t, validateCertificate("testapp", validCertificate("testapp"), now))
+
+	wildcard := testCertificate("*.example.com", now.Add(-time.Hour), now.Add(time.Hour))
+	assert.Nil(t, validateCertificate("api.example.com", wildcard, now))
+}
+
+func TestValidateCertificateShouldRejectInvalidCertificates(t *testing.T) {
+	now := time.Now()
+
+	mismatched := *validCertificate("testapp")
+	mismatched.PrivateKey = validCertificate("other").PrivateKey
+
+	missingKey := *validCertificate("testapp")
+	missingKey.PrivateKey = nil
+
+	invalidKey := *validCertificate("testapp")
+	invalidKey.PrivateKey = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("garbage")})
+
+	// the leaf of a chain must be signed by the next certificate
+	unordered := *validCertificate("testapp")
+	unordered.Certificate = append(
+		append([]byte{}, unordered.Certificate...),
+		validCertificate("other").Certificate...)
+
+	tests := []struct {
+		name     string
+		cert     *frontends.Certificate
+		expected error
+	}{
+		{"missing", &frontends.Certificate{}, ErrCertificateMissing},
+		{"unordered", &unordered, ErrCertificateChainInvalid},
+		{"missing-key", &missingKey, ErrCertificateKeyMissing},
+		{"invalid-key", &invalidKey, ErrCertificateKeyInvalid},
+		{"mismatched", &mismatched, ErrCertificateKeyMismatch},
+		{"host", validCertificate("other"), ErrCertificateHostMismatch},
+		{"expired", testCertificate("testapp", now.Add(-2*time.Hour), now.Add(-time.Hour)), ErrCertificateExpired},
+		{"not-yet-valid", testCertificate("testapp", now.Add(time.Hour), now.Add(2*time.Hour)), ErrCertificateNotYetValid},
+	}
+
+	for _, test := range tests {
+		err := validateCertificate("testapp", test.cert, now)
+		assertCertificateError(t, test.expected, err)
+	}
+}
+
+func TestPublicKeyMatches(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	otherECKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+
+	assert.True(t, publicKeyMatches(&ecKey.PublicKey, ecKey))
+	assert.True(t, publicKeyMatches(&rsaKey.PublicKey, rsaKey))
+
+	assert.False(t, publicKeyMatches(&ecKey.PublicKey, otherECKey))
+	assert.False(t, publicKeyMatches(&rsaKey.PublicKey, otherRSAKey))
+	assert.False(t, publicKeyMatches(&ecKey.PublicKey, rsaKey))
+	assert.False(t, publicKeyMatches(&rsaKey.PublicKey, ecKey))
+}
+
+func TestUpsertFrontendShouldKeepWorkingCertificate(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(&dummyServiceRepository{}, &dummyFrontendRepository{}, web)
+
+	valid := validCertificate("testapp")
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("https://testapp", valid)))
+
+	assert.True(t, valid == web.certs["testapp"])
+	assert.NotContains(t, p.frontendErrors, "testapp")
+
+	expired := testCertificate("testapp", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("https://testapp", expired)))
+
+	assert.True(t, valid == web.certs["testapp"])
+	assertCertificateError(t, ErrCertificateExpired, p.frontendErrors["testapp"])
+
+	status := p.status()
+	if assert.Len(t, status.Frontends, 1) {
+		assertCertificateError(t, ErrCertificateExpired, status.Frontends[0].LastError)
+	}
+
+	// the routes are configured regardless
+	u, _ := url.Parse("https://testapp")
+	assert.Contains(t, web.routes, u.String())
+}
@@ -16,6 +16,10 @@ var (
 	ErrSecureWebServerMissing    = errors.New("secure web server missing")
 	ErrLoadBalancerMissing       = errors.New("load balancer missing")
 	ErrInvalidPollingDuration    = errors.New("invalid polling duration, must greater than or equal to 0")
+	ErrCertificateManagerMissing = errors.New("certificate manager missing")
+	ErrMiddlewareRegistryMissing = errors.New("middleware registry missing")
+	ErrInvalidExpiryThreshold    = errors.New("invalid expiry threshold, must be greater than 0")
+	ErrInvalidExpiryInterval     = errors.New("invalid expiry check interval, must be greater than or equal to 0")
 )
 
 // Command models the Start Proxy Command which can be used to start one of the
@@ -48,21 +52,32 @@ func NewCommand(
 }
 
 // Execute runs the Start Proxy Command by configuring the required listeners.
-func (c *Command) Execute(model *Model) error {
+// It returns a handle to the started proxy.
+func (c *Command) Execute(model *Model) (*Proxy, error) {
 	if model.WebServer == nil {
-		return ErrWebServerMissing
+		return nil, ErrWebServerMissing
 	}
 
 	if model.SecureWebServer == nil {
-		return ErrSecureWebServerMissing
+		return nil, ErrSecureWebServerMissing
 	}
 
 	if model.LoadBalancer == nil {
-		return ErrLoadBalancerMissing
+		return nil, ErrLoadBalancerMissing
 	}
 
 	if model.PollingDuration < 1 {
-		return ErrInvalidPollingDuration
+		return nil, ErrInvalidPollingDuration
+	}
+
+	for _, threshold := range model.ExpiryThresholds {
+		if threshold <= 0 {
+			return nil, ErrInvalidExpiryThreshold
+		}
+	}
+
+	if model.ExpiryCheckInterval < 0 {
+		return nil, ErrInvalidExpiryInterval
 	}
 
 	if model.Ctx == nil {
@@ -84,7 +99,19 @@ func (c *Command) Execute(model *Model) error {
 		model.SecureWebServer,
 		model.LoadBalancer)
 
+	proxy.certificateManager = model.CertificateManager
+	proxy.certificateStore = model.CertificateStore
+	proxy.middlewareRegistry = model.MiddlewareRegistry
+	proxy.setExpiryMonitoring(
+		model.ExpiryThresholds,
+		model.ExpiryCheckInterval,
+		model.MaintenanceHandler)
+
+	// add to the wait group before starting, so that a caller waiting right
+	// after a cancel does not return early
+	model.WaitGroup.Add(1)
+
 	go proxy.run()
 
-	return nil
+	return &Proxy{p: proxy}, nil
 }
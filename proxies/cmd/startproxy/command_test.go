@@ -65,7 +65,7 @@ func TestExecute(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -88,7 +88,7 @@ func TestExecuteShouldLogRepositoryErrors(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -109,7 +109,7 @@ func TestExecuteShouldLogDescribeServiceErrors(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -130,7 +130,7 @@ func TestExecuteShouldLogDescribeFrontendErrors(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -151,7 +151,7 @@ func TestExecuteShouldLogWebServerErrors(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{FailAll: true},
 		SecureWebServer: &dummyWebServer{FailAll: true},
@@ -174,7 +174,7 @@ func TestExecuteShouldLogLoadBalancerErrors(t *testing.T) {
 
 	web := &dummyWebServer{}
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       web,
 		SecureWebServer: web,
@@ -201,7 +201,7 @@ func TestExecuteShouldReturnErrorOnMissingWebServer(t *testing.T) {
 
 	c, _ := NewCommand(sr, fr, logger)
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             context.Background(),
 		SecureWebServer: &dummyWebServer{},
 		LoadBalancer:    &dummyLoadBalancer{},
@@ -218,7 +218,7 @@ func TestExecuteShouldReturnErrorOnMissingSecureWebServer(t *testing.T) {
 
 	c, _ := NewCommand(sr, fr, logger)
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:          context.Background(),
 		WebServer:    &dummyWebServer{},
 		LoadBalancer: &dummyLoadBalancer{},
@@ -235,7 +235,7 @@ func TestExecuteShouldReturnErrorOnMissingLoadBalancer(t *testing.T) {
 
 	c, _ := NewCommand(sr, fr, logger)
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             context.Background(),
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -252,7 +252,7 @@ func TestExecuteShouldAcceptNilContext(t *testing.T) {
 
 	c, _ := NewCommand(sr, fr, logger)
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             nil,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -271,7 +271,7 @@ func TestExecuteShouldReturnErrorOnNegativeDuration(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	err := c.Execute(&Model{
+	_, err := c.Execute(&Model{
 		Ctx:             ctx,
 		WebServer:       &dummyWebServer{},
 		SecureWebServer: &dummyWebServer{},
@@ -285,3 +285,30 @@ func TestExecuteShouldReturnErrorOnNegativeDuration(t *testing.T) {
 
 	cancel()
 }
+
+func TestExecuteShouldReturnErrorOnInvalidExpiryMonitoring(t *testing.T) {
+	sr := &dummyServiceRepository{}
+	fr := &dummyFrontendRepository{}
+
+	c, _ := NewCommand(sr, fr, logger)
+
+	_, err := c.Execute(&Model{
+		WebServer:        &dummyWebServer{},
+		SecureWebServer:  &dummyWebServer{},
+		LoadBalancer:     &dummyLoadBalancer{},
+		PollingDuration:  60 * time.Second,
+		ExpiryThresholds: []time.Duration{24 * time.Hour, 0},
+	})
+
+	assert.Equal(t, ErrInvalidExpiryThreshold, err)
+
+	_, err = c.Execute(&Model{
+		WebServer:           &dummyWebServer{},
+		SecureWebServer:     &dummyWebServer{},
+		LoadBalancer:        &dummyLoadBalancer{},
+		PollingDuration:     60 * time.Second,
+		ExpiryCheckInterval: -1 * time.Second,
+	})
+
+	assert.Equal(t, ErrInvalidExpiryInterval, err)
+}
@@ -0,0 +1,37 @@
+package startproxy
+
+import (
+	"errors"
+	"net/http"
+)
+
+type dummyCertificateManager struct {
+	FailAll bool
+	managed map[string]int
+}
+
+func (m *dummyCertificateManager) ChallengeHandler() http.Handler {
+	return namedHandler("challenge")
+}
+
+func (m *dummyCertificateManager) ManageCertificate(domainName string) error {
+	if m.FailAll {
+		return errors.New("ManageCertificate(" + domainName + ")")
+	}
+
+	if m.managed == nil {
+		m.managed = make(map[string]int)
+	}
+
+	m.managed[domainName]++
+
+	return nil
+}
+
+func (m *dummyCertificateManager) UnmanageCertificate(domainName string) {
+	m.managed[domainName]--
+
+	if m.managed[domainName] < 1 {
+		delete(m.managed, domainName)
+	}
+}
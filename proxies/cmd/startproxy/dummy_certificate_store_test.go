@@ -0,0 +1,61 @@
+package startproxy
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+type dummyCertificateStore struct {
+	FailAll bool
+	certs   map[string]*interfaces.StoredCertificate
+}
+
+func (s *dummyCertificateStore) ListCertificates() ([]*interfaces.StoredCertificate, error) {
+	if s.FailAll {
+		return nil, errors.New("ListCertificates()")
+	}
+
+	var certs []*interfaces.StoredCertificate
+	for _, cert := range s.certs {
+		certs = append(certs, cert)
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].DomainName < certs[j].DomainName
+	})
+
+	return certs, nil
+}
+
+func (s *dummyCertificateStore) DescribeCertificate(domainName string) (*interfaces.StoredCertificate, error) {
+	cert, found := s.certs[domainName]
+	if !found {
+		return nil, interfaces.ErrUnknownCertificate
+	}
+
+	return cert, nil
+}
+
+func (s *dummyCertificateStore) PutCertificate(domainName string, cert *frontends.Certificate) (*interfaces.StoredCertificate, error) {
+	if s.FailAll {
+		return nil, errors.New("PutCertificate(" + domainName + ")")
+	}
+
+	if s.certs == nil {
+		s.certs = make(map[string]*interfaces.StoredCertificate)
+	}
+
+	stored := &interfaces.StoredCertificate{
+		DomainName:  domainName,
+		Certificate: cert,
+		NotAfter:    time.Now().Add(time.Hour),
+	}
+
+	s.certs[domainName] = stored
+
+	return stored, nil
+}
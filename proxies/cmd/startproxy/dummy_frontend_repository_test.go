@@ -58,13 +58,15 @@ func (r *dummyFrontendRepository) Subscribe() <-chan interfaces.FrontendEvent {
 	return events
 }
 
+func (r *dummyFrontendRepository) Unsubscribe(events <-chan interfaces.FrontendEvent) {}
+
 func mockFrontend(name string) *frontends.Frontend {
 	var scheme = "http://"
 	var cert *frontends.Certificate
 
 	if strings.HasPrefix(name, "secure-") {
 		scheme = "https://"
-		cert = &frontends.Certificate{}
+		cert = validCertificate(name)
 	}
 
 	f, err := frontends.NewFrontend(name, scheme+name, cert, name)
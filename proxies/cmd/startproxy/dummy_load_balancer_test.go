@@ -8,6 +8,7 @@ import (
 
 type dummyLoadBalancer struct {
 	FailAll bool
+	deleted []string
 }
 
 func (lb *dummyLoadBalancer) UpsertService(name string, urls ...*url.URL) (http.Handler, error) {
@@ -22,5 +23,5 @@ func (lb *dummyLoadBalancer) UpsertService(name string, urls ...*url.URL) (http.
 }
 
 func (lb *dummyLoadBalancer) DeleteService(name string) {
-	// do nothing
+	lb.deleted = append(lb.deleted, name)
 }
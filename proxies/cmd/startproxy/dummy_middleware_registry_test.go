@@ -0,0 +1,65 @@
+package startproxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+var errInvalidLabel = errors.New("invalid label")
+
+// dummyMiddlewareRegistry creates middlewares that write their name before
+// calling the next handler. Middlewares named counter are reconfigurable.
+type dummyMiddlewareRegistry struct{}
+
+func (r *dummyMiddlewareRegistry) CreateMiddleware(config *interfaces.MiddlewareConfig) (interfaces.Middleware, error) {
+	if config.Name == "unknown" {
+		return nil, interfaces.ErrUnknownMiddleware
+	}
+
+	if config.Name == "counter" {
+		if config.Options["label"] == "invalid" {
+			return nil, errInvalidLabel
+		}
+
+		return &counterMiddleware{label: config.Options["label"]}, nil
+	}
+
+	name := config.Name
+
+	return interfaces.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + ","))
+
+			next.ServeHTTP(w, r)
+		})
+	}), nil
+}
+
+// counterMiddleware writes its label and the number of requests it handled.
+type counterMiddleware struct {
+	label string
+	count int
+}
+
+func (m *counterMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.count++
+
+		fmt.Fprintf(w, "%s%d,", m.label, m.count)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *counterMiddleware) Reconfigure(options map[string]string) error {
+	if options["label"] == "invalid" {
+		return errInvalidLabel
+	}
+
+	m.label = options["label"]
+
+	return nil
+}
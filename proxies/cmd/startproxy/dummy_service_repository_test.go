@@ -56,6 +56,8 @@ func (r *dummyServiceRepository) Subscribe() <-chan interfaces.ServiceEvent {
 	return events
 }
 
+func (r *dummyServiceRepository) Unsubscribe(events <-chan interfaces.ServiceEvent) {}
+
 func mockService(name string) *services.Service {
 	f, err := services.NewService(name, "http://127.0.0.1:8080", "http://127.0.0.1:8080")
 	if err != nil {
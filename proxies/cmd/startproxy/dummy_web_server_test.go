@@ -5,25 +5,34 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 
+	"github.com/off-sync/platform-proxy-app/interfaces"
 	"github.com/off-sync/platform-proxy-domain/frontends"
 )
 
 type dummyWebServer struct {
-	FailAll bool
-	routes  map[string]http.Handler
+	FailAll    bool
+	FailRoutes map[string]bool
+	routes     map[string]http.Handler
+	certs      map[string]*frontends.Certificate
 }
 
 func (s *dummyWebServer) checkState() {
 	if s.routes == nil {
 		s.routes = make(map[string]http.Handler)
 	}
+
+	if s.certs == nil {
+		s.certs = make(map[string]*frontends.Certificate)
+	}
 }
 
 func (s *dummyWebServer) UpsertRoute(route *url.URL, handler http.Handler) error {
 	s.checkState()
 
-	if s.FailAll {
+	if s.FailAll || s.FailRoutes[route.String()] {
 		return fmt.Errorf("UpsertRoute(%v, %v)", route, handler)
 	}
 
@@ -43,9 +52,36 @@ func (s *dummyWebServer) UpsertCertificate(domainName string, cert *frontends.Ce
 		return fmt.Errorf("UpsertCertificate(%s, %v)", domainName, cert)
 	}
 
+	s.checkState()
+
+	s.certs[domainName] = cert
+
 	return nil
 }
 
+func (s *dummyWebServer) InstalledCertificates() []*interfaces.InstalledCertificate {
+	var installed []*interfaces.InstalledCertificate
+
+	for domainName, cert := range s.certs {
+		chain, err := parseChain(cert.Certificate)
+		if err != nil {
+			continue
+		}
+
+		installed = append(installed, &interfaces.InstalledCertificate{
+			DomainName: strings.ToLower(domainName),
+			NotBefore:  chain[0].NotBefore,
+			NotAfter:   chain[0].NotAfter,
+		})
+	}
+
+	sort.Slice(installed, func(i, j int) bool {
+		return installed[i].DomainName < installed[j].DomainName
+	})
+
+	return installed
+}
+
 type dummyResponseWriter struct {
 	bytes.Buffer
 	header http.Header
@@ -0,0 +1,219 @@
+package startproxy
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+const day = 24 * time.Hour
+
+// Expiry monitoring defaults, used for zero values in the Model.
+var (
+	DefaultExpiryThresholds    = []time.Duration{30 * day, 14 * day, 7 * day, day}
+	DefaultExpiryCheckInterval = time.Hour
+)
+
+// certificateExpiry keeps track of the warnings logged for an installed
+// certificate.
+type certificateExpiry struct {
+	notAfter time.Time
+
+	// warned is the number of thresholds crossed at the last check
+	warned  int
+	expired bool
+}
+
+// setExpiryMonitoring configures the expiry monitoring, applying the
+// defaults for zero values.
+func (p *proxy) setExpiryMonitoring(
+	thresholds []time.Duration,
+	interval time.Duration,
+	maintenanceHandler http.Handler) {
+	if len(thresholds) == 0 {
+		thresholds = DefaultExpiryThresholds
+	}
+
+	if interval == 0 {
+		interval = DefaultExpiryCheckInterval
+	}
+
+	// order thresholds from the longest to the shortest duration
+	p.expiryThresholds = append([]time.Duration{}, thresholds...)
+	sort.Slice(p.expiryThresholds, func(i, j int) bool {
+		return p.expiryThresholds[i] > p.expiryThresholds[j]
+	})
+
+	p.expiryCheckInterval = interval
+	p.maintenanceHandler = maintenanceHandler
+}
+
+// checkCertificates inspects the certificates installed on the secure web
+// server. For each certificate a warning is logged when it crosses one of
+// the expiry thresholds, and an error once it has expired. If a maintenance
+// handler is set, the secure frontends whose certificate expired or was
+// replaced are reconfigured.
+func (p *proxy) checkCertificates() {
+	inspector, ok := p.secureWebServer.(interfaces.CertificateInspector)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+
+	for _, cert := range inspector.InstalledCertificates() {
+		seen[cert.DomainName] = true
+
+		expiry, found := p.expiryStates[cert.DomainName]
+		if !found || !expiry.notAfter.Equal(cert.NotAfter) {
+			// new or replaced certificate
+			expiry = &certificateExpiry{notAfter: cert.NotAfter}
+			p.expiryStates[cert.DomainName] = expiry
+		}
+
+		p.checkCertificate(cert.DomainName, expiry, now)
+	}
+
+	for domainName := range p.expiryStates {
+		if !seen[domainName] {
+			delete(p.expiryStates, domainName)
+		}
+	}
+
+	if p.maintenanceHandler == nil {
+		return
+	}
+
+	for name, config := range p.frontendConfigs {
+		if config.isSecure && config.maintenance != p.certificateExpired(config.url.Hostname(), now) {
+			p.configureFrontend(name)
+		}
+	}
+}
+
+func (p *proxy) checkCertificate(domainName string, expiry *certificateExpiry, now time.Time) {
+	remaining := expiry.notAfter.Sub(now)
+
+	if remaining <= 0 {
+		if !expiry.expired {
+			p.logger.
+				WithField("domain_name", domainName).
+				WithField("not_after", expiry.notAfter).
+				Error("certificate has expired")
+		}
+
+		expiry.expired = true
+
+		return
+	}
+
+	crossed := 0
+
+	for _, threshold := range p.expiryThresholds {
+		if remaining <= threshold {
+			crossed++
+		}
+	}
+
+	if crossed > expiry.warned {
+		p.logger.
+			WithField("domain_name", domainName).
+			WithField("not_after", expiry.notAfter).
+			WithField("days_to_expiry", daysToExpiry(expiry.notAfter, now)).
+			WithField("threshold_days", int(p.expiryThresholds[crossed-1]/day)).
+			Warn("certificate expires soon")
+	}
+
+	expiry.warned = crossed
+}
+
+// certificateExpired returns true if the certificate installed for host has
+// expired at time now.
+func (p *proxy) certificateExpired(host string, now time.Time) bool {
+	inspector, ok := p.secureWebServer.(interfaces.CertificateInspector)
+	if !ok {
+		return false
+	}
+
+	cert := findCertificate(inspector.InstalledCertificates(), host)
+
+	return cert != nil && !now.Before(cert.NotAfter)
+}
+
+func (p *proxy) certificateExpiries() []*interfaces.CertificateExpiry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	inspector, ok := p.secureWebServer.(interfaces.CertificateInspector)
+	if !ok {
+		return nil
+	}
+
+	certs := inspector.InstalledCertificates()
+
+	hosts := make(map[string]bool)
+	for _, config := range p.frontendConfigs {
+		if config.isSecure {
+			hosts[strings.ToLower(config.url.Hostname())] = true
+		}
+	}
+
+	sortedHosts := make([]string, 0, len(hosts))
+	for host := range hosts {
+		sortedHosts = append(sortedHosts, host)
+	}
+
+	sort.Strings(sortedHosts)
+
+	now := time.Now()
+
+	var expiries []*interfaces.CertificateExpiry
+
+	for _, host := range sortedHosts {
+		cert := findCertificate(certs, host)
+		if cert == nil {
+			continue
+		}
+
+		expiries = append(expiries, &interfaces.CertificateExpiry{
+			Host:         host,
+			DomainName:   cert.DomainName,
+			NotAfter:     cert.NotAfter,
+			DaysToExpiry: daysToExpiry(cert.NotAfter, now),
+			Expired:      !now.Before(cert.NotAfter),
+		})
+	}
+
+	return expiries
+}
+
+// findCertificate returns the certificate used for host, falling back to a
+// wildcard certificate for its parent domain, or nil if there is none.
+func findCertificate(certs []*interfaces.InstalledCertificate, host string) *interfaces.InstalledCertificate {
+	host = strings.ToLower(host)
+
+	var wildcard *interfaces.InstalledCertificate
+
+	for _, cert := range certs {
+		if cert.DomainName == host {
+			return cert
+		}
+
+		if i := strings.Index(host, "."); i > 0 && cert.DomainName == "*"+host[i:] {
+			wildcard = cert
+		}
+	}
+
+	return wildcard
+}
+
+// daysToExpiry returns the number of whole days from now until notAfter,
+// rounded down.
+func daysToExpiry(notAfter, now time.Time) int {
+	return int(math.Floor(float64(notAfter.Sub(now)) / float64(day)))
+}
@@ -0,0 +1,185 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func newExpiryTestProxy(web *dummyWebServer) (*proxy, *test.Hook) {
+	l, hook := test.NewNullLogger()
+	l.Level = logrus.DebugLevel
+
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{frontendNames: []string{"secure-testapp"}},
+		web)
+	p.logger = logging.NewLogrusLogger(l)
+	p.setExpiryMonitoring(nil, 0, nil)
+
+	return p, hook
+}
+
+func countEntries(hook *test.Hook, level logrus.Level, msg string) int {
+	count := 0
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == level && entry.Message == msg {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestSetExpiryMonitoringShouldApplyDefaults(t *testing.T) {
+	p := newTestProxy(&dummyServiceRepository{}, &dummyFrontendRepository{}, &dummyWebServer{})
+
+	p.setExpiryMonitoring(nil, 0, nil)
+
+	assert.Equal(t, DefaultExpiryThresholds, p.expiryThresholds)
+	assert.Equal(t, DefaultExpiryCheckInterval, p.expiryCheckInterval)
+
+	p.setExpiryMonitoring([]time.Duration{day, 7 * day}, time.Minute, nil)
+
+	assert.Equal(t, []time.Duration{7 * day, day}, p.expiryThresholds)
+	assert.Equal(t, time.Minute, p.expiryCheckInterval)
+}
+
+func TestCheckCertificatesShouldWarnOncePerThreshold(t *testing.T) {
+	web := &dummyWebServer{}
+	p, hook := newExpiryTestProxy(web)
+
+	web.checkState()
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-time.Hour), time.Now().Add(10*day))
+
+	p.checkCertificates()
+	p.checkCertificates()
+
+	assert.Equal(t, 1, countEntries(hook, logrus.WarnLevel, "certificate expires soon"))
+	assert.Equal(t, 14, hook.LastEntry().Data["threshold_days"])
+	assert.Equal(t, 9, hook.LastEntry().Data["days_to_expiry"])
+
+	// crossing the next threshold
+	p.expiryStates["testapp"].notAfter = time.Now().Add(5 * day)
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-time.Hour), p.expiryStates["testapp"].notAfter)
+
+	p.checkCertificates()
+
+	assert.Equal(t, 2, countEntries(hook, logrus.WarnLevel, "certificate expires soon"))
+	assert.Equal(t, 7, hook.LastEntry().Data["threshold_days"])
+
+	// expiring
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+
+	p.checkCertificates()
+	p.checkCertificates()
+
+	assert.Equal(t, 1, countEntries(hook, logrus.ErrorLevel, "certificate has expired"))
+
+	// removed certificates are no longer tracked
+	delete(web.certs, "testapp")
+
+	p.checkCertificates()
+
+	assert.Empty(t, p.expiryStates)
+}
+
+func TestCheckCertificatesShouldResetWarningsForReplacedCertificates(t *testing.T) {
+	web := &dummyWebServer{}
+	p, hook := newExpiryTestProxy(web)
+
+	web.checkState()
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-time.Hour), time.Now().Add(10*day))
+
+	p.checkCertificates()
+
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-time.Hour), time.Now().Add(90*day))
+
+	p.checkCertificates()
+
+	web.certs["testapp"] = testCertificate("testapp", time.Now().Add(-time.Hour), time.Now().Add(10*day))
+
+	p.checkCertificates()
+
+	assert.Equal(t, 2, countEntries(hook, logrus.WarnLevel, "certificate expires soon"))
+}
+
+func TestCheckCertificatesShouldSwitchToMaintenance(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newAutoTLSTestProxy(web, &dummyCertificateManager{})
+	p.setExpiryMonitoring(nil, 0, namedHandler("maintenance"))
+
+	p.configureFrontend("testapp")
+
+	secureURL, _ := url.Parse("https://testapp")
+	plainURL, _ := url.Parse("http://testapp")
+	challengeURL, _ := url.Parse("http://testapp/.well-known/acme-challenge")
+
+	assert.Equal(t, "service", web.Handle(secureURL, &http.Request{}))
+
+	// the certificate expires, as it could not be renewed
+	web.checkState()
+	web.certs["testapp"] = testCertificate("testapp",
+		time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+
+	p.checkCertificates()
+
+	assert.True(t, p.frontendConfigs["testapp"].maintenance)
+	assert.Equal(t, "maintenance", web.Handle(secureURL, &http.Request{}))
+	assert.Equal(t, "maintenance", web.Handle(plainURL, &http.Request{}))
+	assert.Equal(t, "challenge", web.Handle(challengeURL, &http.Request{}))
+
+	// a renewed certificate is installed
+	web.certs["testapp"] = validCertificate("testapp")
+
+	p.checkCertificates()
+
+	assert.False(t, p.frontendConfigs["testapp"].maintenance)
+	assert.Equal(t, "service", web.Handle(secureURL, &http.Request{}))
+}
+
+func TestCertificateExpiries(t *testing.T) {
+	web := &dummyWebServer{}
+	p, _ := newExpiryTestProxy(web)
+	p.serviceHandlers["secure-testapp"] = namedHandler("service")
+
+	p.configureFrontend("secure-testapp")
+
+	expiries := p.certificateExpiries()
+
+	if assert.Len(t, expiries, 1) {
+		assert.Equal(t, "secure-testapp", expiries[0].Host)
+		assert.Equal(t, "secure-testapp", expiries[0].DomainName)
+		assert.Equal(t, 0, expiries[0].DaysToExpiry)
+		assert.False(t, expiries[0].Expired)
+	}
+}
+
+func TestFindCertificateShouldFallBackToWildcard(t *testing.T) {
+	certs := []*interfaces.InstalledCertificate{
+		{DomainName: "*.example.com"},
+		{DomainName: "api.example.com"},
+	}
+
+	assert.Equal(t, certs[1], findCertificate(certs, "API.example.com"))
+	assert.Equal(t, certs[0], findCertificate(certs, "www.example.com"))
+	assert.Nil(t, findCertificate(certs, "example.com"))
+	assert.Nil(t, findCertificate(certs, "a.b.example.com"))
+}
+
+func TestDaysToExpiry(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, 1, daysToExpiry(now.Add(36*time.Hour), now))
+	assert.Equal(t, 0, daysToExpiry(now.Add(time.Hour), now))
+	assert.Equal(t, -1, daysToExpiry(now.Add(-time.Hour), now))
+}
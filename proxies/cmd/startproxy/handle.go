@@ -0,0 +1,161 @@
+package startproxy
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Proxy is a handle to a proxy started by the Start Proxy Command.
+type Proxy struct {
+	p *proxy
+}
+
+// Status provides a snapshot of the services and frontends configured on a
+// proxy.
+type Status struct {
+	Services  []*ServiceStatus
+	Frontends []*FrontendStatus
+}
+
+// ServiceStatus describes a configured service.
+type ServiceStatus struct {
+	Name string
+}
+
+// FrontendStatus describes a configured frontend.
+type FrontendStatus struct {
+	Name        string
+	URL         *url.URL
+	ServiceName string
+	IsSecure    bool
+
+	// InMaintenance is true if the frontend serves the maintenance handler,
+	// as its certificate has expired.
+	InMaintenance bool
+
+	// LastError is the last error encountered configuring the frontend, such
+	// as a *CertificateError, or nil if the last configuration succeeded.
+	LastError error
+}
+
+// Ready returns a channel that is closed once the proxy has completed its
+// first full configuration.
+func (h *Proxy) Ready() <-chan struct{} {
+	return h.p.ready
+}
+
+// Wait blocks until the proxy has stopped.
+func (h *Proxy) Wait() {
+	<-h.p.done
+}
+
+// Stop stops the proxy and waits for it to finish. If ctx is done before the
+// proxy has stopped its error is returned.
+func (h *Proxy) Stop(ctx context.Context) error {
+	h.p.cancel()
+
+	select {
+	case <-h.p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns a snapshot of the configured services and frontends, ordered
+// by name.
+func (h *Proxy) Status() *Status {
+	return h.p.status()
+}
+
+// Routes returns the routes installed on the web servers, ordered by frontend
+// name. It implements interfaces.RouteTable.
+func (h *Proxy) Routes() []*interfaces.Route {
+	return h.p.routes()
+}
+
+// CertificateExpiries returns the expiry of the certificates serving the
+// hosts of the secure frontends, ordered by host. It implements
+// interfaces.CertificateExpiryReporter.
+func (h *Proxy) CertificateExpiries() []*interfaces.CertificateExpiry {
+	return h.p.certificateExpiries()
+}
+
+func (p *proxy) routes() []*interfaces.Route {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := make([]string, 0, len(p.frontendConfigs))
+	for name := range p.frontendConfigs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var routes []*interfaces.Route
+
+	for _, name := range names {
+		config := p.frontendConfigs[name]
+
+		lastErr := p.frontendErrors[name]
+		if lastErr == nil {
+			lastErr = p.serviceErrors[config.serviceName]
+		}
+
+		for _, r := range config.routes {
+			routes = append(routes, &interfaces.Route{
+				FrontendName:   name,
+				URL:            r.url,
+				IsSecure:       r.isSecure,
+				IsRedirect:     r.isRedirect,
+				IsChallenge:    r.isChallenge,
+				IsMaintenance:  r.isMaintenance,
+				ServiceName:    config.serviceName,
+				ServiceHandler: config.serviceHandlerKind,
+				LastError:      lastErr,
+			})
+		}
+	}
+
+	return routes
+}
+
+func (p *proxy) status() *Status {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := &Status{
+		Services:  make([]*ServiceStatus, 0, len(p.serviceHandlers)),
+		Frontends: make([]*FrontendStatus, 0, len(p.frontendConfigs)),
+	}
+
+	for name := range p.serviceHandlers {
+		status.Services = append(status.Services, &ServiceStatus{
+			Name: name,
+		})
+	}
+
+	for name, config := range p.frontendConfigs {
+		status.Frontends = append(status.Frontends, &FrontendStatus{
+			Name:          name,
+			URL:           config.url,
+			ServiceName:   config.serviceName,
+			IsSecure:      config.isSecure,
+			InMaintenance: config.maintenance,
+			LastError:     p.frontendErrors[name],
+		})
+	}
+
+	sort.Slice(status.Services, func(i, j int) bool {
+		return status.Services[i].Name < status.Services[j].Name
+	})
+
+	sort.Slice(status.Frontends, func(i, j int) bool {
+		return status.Frontends[i].Name < status.Frontends[j].Name
+	})
+
+	return status
+}
@@ -0,0 +1,191 @@
+package startproxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/memrepo"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestProxyHandle(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp", "secure-testapp"}}
+
+	c, _ := NewCommand(sr, fr, logger)
+
+	wg := &sync.WaitGroup{}
+
+	p, err := c.Execute(&Model{
+		WaitGroup:       wg,
+		WebServer:       &dummyWebServer{},
+		SecureWebServer: &dummyWebServer{},
+		LoadBalancer:    &dummyLoadBalancer{},
+		PollingDuration: 60 * time.Second,
+	})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, p)
+
+	select {
+	case <-p.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("proxy not ready")
+	}
+
+	status := p.Status()
+
+	assert.Len(t, status.Services, 1)
+	assert.Equal(t, "testapp", status.Services[0].Name)
+
+	assert.Len(t, status.Frontends, 2)
+	assert.Equal(t, "secure-testapp", status.Frontends[0].Name)
+	assert.True(t, status.Frontends[0].IsSecure)
+	assert.Equal(t, "testapp", status.Frontends[1].Name)
+	assert.Equal(t, "http://testapp", status.Frontends[1].URL.String())
+	assert.Equal(t, "testapp", status.Frontends[1].ServiceName)
+
+	assert.Nil(t, p.Stop(context.Background()))
+
+	// both must return once stopped
+	p.Wait()
+	wg.Wait()
+}
+
+func TestProxyHandleStopsWhenContextIsCancelled(t *testing.T) {
+	c, _ := NewCommand(&dummyServiceRepository{}, &dummyFrontendRepository{}, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	p, _ := c.Execute(&Model{
+		Ctx:             ctx,
+		WaitGroup:       wg,
+		WebServer:       &dummyWebServer{},
+		SecureWebServer: &dummyWebServer{},
+		LoadBalancer:    &dummyLoadBalancer{},
+		PollingDuration: 60 * time.Second,
+	})
+
+	// cancel immediately, the wait group must still be waited for
+	cancel()
+	wg.Wait()
+
+	p.Wait()
+}
+
+func TestProxyHandleStopShouldReturnContextError(t *testing.T) {
+	p := newTestProxy(&dummyServiceRepository{}, &dummyFrontendRepository{}, &dummyWebServer{})
+
+	// the proxy is never run, so it never stops
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := (&Proxy{p: p}).Stop(ctx)
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestProxyRoutes(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp", "secure-testapp", "noservice"}}
+
+	p := newTestProxy(sr, fr, &dummyWebServer{})
+	p.configure()
+
+	routes := (&Proxy{p: p}).Routes()
+
+	assert.Len(t, routes, 4)
+
+	// ordered by frontend name
+	assert.Equal(t, "noservice", routes[0].FrontendName)
+	assert.Equal(t, interfaces.ServiceHandlerNotFound, routes[0].ServiceHandler)
+
+	assert.Equal(t, "secure-testapp", routes[1].FrontendName)
+	assert.Equal(t, "https://secure-testapp", routes[1].URL.String())
+	assert.True(t, routes[1].IsSecure)
+	assert.False(t, routes[1].IsRedirect)
+
+	assert.Equal(t, "secure-testapp", routes[2].FrontendName)
+	assert.Equal(t, "http://secure-testapp", routes[2].URL.String())
+	assert.False(t, routes[2].IsSecure)
+	assert.True(t, routes[2].IsRedirect)
+
+	assert.Equal(t, "testapp", routes[3].FrontendName)
+	assert.Equal(t, "testapp", routes[3].ServiceName)
+	assert.Equal(t, interfaces.ServiceHandlerLoadBalancer, routes[3].ServiceHandler)
+	assert.Nil(t, routes[3].LastError)
+}
+
+func TestProxyRoutesShouldReportErrors(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp", "secure-testapp"}}
+	web := &dummyWebServer{}
+
+	p := newProxy(
+		context.Background(),
+		&sync.WaitGroup{},
+		logger,
+		sr,
+		fr,
+		time.Minute,
+		web,
+		web,
+		&dummyLoadBalancer{FailAll: true})
+
+	p.configure()
+
+	routes := (&Proxy{p: p}).Routes()
+
+	assert.Len(t, routes, 3)
+
+	// secure-testapp has no service
+	assert.Equal(t, interfaces.ServiceHandlerNotFound, routes[0].ServiceHandler)
+	assert.Nil(t, routes[0].LastError)
+
+	assert.Equal(t, "testapp", routes[2].FrontendName)
+	assert.Equal(t, interfaces.ServiceHandlerError, routes[2].ServiceHandler)
+	assert.NotNil(t, routes[2].LastError)
+}
+
+func TestProxyWithMemoryRepositories(t *testing.T) {
+	sr, _ := memrepo.NewServiceRepository(mockService("testapp"))
+	fr, _ := memrepo.NewFrontendRepository()
+
+	c, _ := NewCommand(sr, fr, logger)
+
+	p, _ := c.Execute(&Model{
+		WebServer:       &dummyWebServer{},
+		SecureWebServer: &dummyWebServer{},
+		LoadBalancer:    &dummyLoadBalancer{},
+		PollingDuration: 60 * time.Second,
+	})
+
+	defer p.Stop(context.Background())
+
+	<-p.Ready()
+
+	fr.Put(mockFrontend("testapp"))
+
+	waitForRoutes(t, p, 1)
+
+	fr.Delete("testapp")
+
+	waitForRoutes(t, p, 0)
+}
+
+func waitForRoutes(t *testing.T, p *Proxy, count int) {
+	deadline := time.Now().Add(time.Second)
+
+	for len(p.Routes()) != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d routes, got %d", count, len(p.Routes()))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,132 @@
+package startproxy
+
+import (
+	"net/http"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// frontendMiddleware is a middleware created for a frontend.
+type frontendMiddleware struct {
+	name       string
+	middleware interfaces.Middleware
+
+	// reused is true if the middleware was taken from the previous config,
+	// in which case options are applied once the routes are installed
+	reused  bool
+	options map[string]string
+}
+
+// frontendHandler returns the handler for the requests of a frontend: the
+// service handler wrapped by the path rewrite and then by the middlewares,
+// which see the original path. It also returns the middlewares, so they can
+// be reused when the frontend is reconfigured.
+func (p *proxy) frontendHandler(
+	options *interfaces.FrontendOptions,
+	serviceHandler http.Handler,
+	previous *frontendConfig) (http.Handler, []*frontendMiddleware, error) {
+	if options == nil {
+		return serviceHandler, nil, nil
+	}
+
+	handler, err := newRewriteHandler(options.PathRewrite, serviceHandler)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	middlewares, err := p.createMiddlewares(options.Middlewares, previous)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the first middleware handles a request first
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i].middleware.Wrap(handler)
+	}
+
+	return handler, middlewares, nil
+}
+
+// createMiddlewares creates the declared middlewares using the middleware
+// registry. Reconfigurable middlewares of the previous config are reused
+// instead, matching them by name in order, so that their state is kept. The
+// options of reused middlewares are validated by creating a new middleware,
+// but only applied by reconfigureMiddlewares, as the middlewares are still in
+// use by the installed routes.
+func (p *proxy) createMiddlewares(
+	configs []*interfaces.MiddlewareConfig,
+	previous *frontendConfig) ([]*frontendMiddleware, error) {
+	if len(configs) < 1 {
+		return nil, nil
+	}
+
+	if p.middlewareRegistry == nil {
+		return nil, ErrMiddlewareRegistryMissing
+	}
+
+	var reusable []*frontendMiddleware
+	if previous != nil {
+		reusable = append(reusable, previous.middlewares...)
+	}
+
+	middlewares := make([]*frontendMiddleware, len(configs))
+
+	for i, config := range configs {
+		m, err := p.middlewareRegistry.CreateMiddleware(config)
+		if err != nil {
+			return nil, err
+		}
+
+		if r := takeReconfigurable(&reusable, config.Name); r != nil {
+			middlewares[i] = &frontendMiddleware{
+				name:       config.Name,
+				middleware: r,
+				reused:     true,
+				options:    config.Options,
+			}
+
+			continue
+		}
+
+		middlewares[i] = &frontendMiddleware{name: config.Name, middleware: m}
+	}
+
+	return middlewares, nil
+}
+
+// reconfigureMiddlewares applies the options to the middlewares reused from
+// the previous config. It must be called once the routes using them are
+// installed, so that a failed update leaves the middlewares unchanged.
+func reconfigureMiddlewares(middlewares []*frontendMiddleware) error {
+	var lastErr error
+
+	for _, m := range middlewares {
+		if !m.reused {
+			continue
+		}
+
+		err := m.middleware.(interfaces.ReconfigurableMiddleware).Reconfigure(m.options)
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+// takeReconfigurable removes the first reconfigurable middleware with the
+// name from middlewares and returns it, or returns nil if there is none.
+func takeReconfigurable(middlewares *[]*frontendMiddleware, name string) interfaces.ReconfigurableMiddleware {
+	for i, m := range *middlewares {
+		r, ok := m.middleware.(interfaces.ReconfigurableMiddleware)
+		if !ok || m.name != name {
+			continue
+		}
+
+		*middlewares = append((*middlewares)[:i], (*middlewares)[i+1:]...)
+
+		return r
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-app/proxies/middleware"
+)
+
+func newMiddlewareTestProxy(web *dummyWebServer, middlewares ...string) (*proxy, *optionsFrontendRepository) {
+	options := &interfaces.FrontendOptions{}
+	for _, name := range middlewares {
+		options.Middlewares = append(options.Middlewares, &interfaces.MiddlewareConfig{Name: name})
+	}
+
+	fr := &optionsFrontendRepository{
+		dummyFrontendRepository: dummyFrontendRepository{frontendNames: []string{"testapp"}},
+		options: map[string]*interfaces.FrontendOptions{
+			"testapp": options,
+		},
+	}
+
+	p := newTestProxy(&dummyServiceRepository{serviceNames: []string{"testapp"}}, fr, web)
+	p.middlewareRegistry = &dummyMiddlewareRegistry{}
+	p.serviceHandlers["testapp"] = namedHandler("service")
+
+	return p, fr
+}
+
+func TestUpsertFrontendShouldComposeMiddlewares(t *testing.T) {
+	web := &dummyWebServer{}
+	p, _ := newMiddlewareTestProxy(web, "auth", "rateLimit")
+
+	p.configureFrontend("testapp")
+
+	u, _ := url.Parse("http://testapp")
+	assert.Equal(t, "auth,rateLimit,service", web.Handle(u, &http.Request{}))
+}
+
+func TestUpsertFrontendShouldKeepRoutesOnMiddlewareErrors(t *testing.T) {
+	web := &dummyWebServer{}
+	p, fr := newMiddlewareTestProxy(web, "auth")
+
+	p.configureFrontend("testapp")
+
+	fr.options["testapp"].Middlewares[0].Name = "unknown"
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, interfaces.ErrUnknownMiddleware, p.frontendErrors["testapp"])
+
+	u, _ := url.Parse("http://testapp")
+	assert.Equal(t, "auth,service", web.Handle(u, &http.Request{}))
+}
+
+func TestUpsertFrontendShouldReportMissingMiddlewareRegistry(t *testing.T) {
+	web := &dummyWebServer{}
+	p, _ := newMiddlewareTestProxy(web, "auth")
+	p.middlewareRegistry = nil
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, ErrMiddlewareRegistryMissing, p.frontendErrors["testapp"])
+	assert.Empty(t, web.routes)
+}
+
+func TestUpsertServiceShouldRebindFrontends(t *testing.T) {
+	web := &dummyWebServer{}
+	p, _ := newMiddlewareTestProxy(web, "auth")
+
+	// the frontend is configured before its service
+	delete(p.serviceHandlers, "testapp")
+
+	p.configureFrontend("testapp")
+
+	u, _ := url.Parse("http://testapp")
+	assert.Contains(t, web.Handle(u, &http.Request{}), "404 page not found")
+
+	p.configureService("testapp")
+
+	assert.Contains(t, web.Handle(u, &http.Request{}), "auth,Service: testapp")
+	assert.Equal(t, interfaces.ServiceHandlerLoadBalancer, p.frontendConfigs["testapp"].serviceHandlerKind)
+}
+
+func TestSameHandler(t *testing.T) {
+	assert.True(t, sameHandler(serviceErrorHandler{}, serviceErrorHandler{}))
+	assert.False(t, sameHandler(namedHandler("a"), namedHandler("a")))
+	assert.False(t, sameHandler(serviceErrorHandler{}, nil))
+	assert.False(t, sameHandler(serviceErrorHandler{}, http.NotFoundHandler()))
+}
+
+func TestUpsertFrontendShouldReconfigureMiddlewares(t *testing.T) {
+	web := &dummyWebServer{}
+	p, fr := newMiddlewareTestProxy(web, "counter", "auth")
+
+	u, _ := url.Parse("http://testapp")
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, "1,auth,service", web.Handle(u, &http.Request{}))
+
+	// the counter keeps its state across reconfigurations
+	fr.options["testapp"].Middlewares[0].Options = map[string]string{"label": "n"}
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, "n2,auth,service", web.Handle(u, &http.Request{}))
+
+	// also when the service is reconfigured
+	p.configureService("testapp")
+
+	assert.Contains(t, web.Handle(u, &http.Request{}), "n3,auth,Service: testapp")
+
+	// invalid options keep the previous routes
+	fr.options["testapp"].Middlewares[0].Options = map[string]string{"label": "invalid"}
+
+	p.configureFrontend("testapp")
+
+	assert.EqualError(t, p.frontendErrors["testapp"], "invalid label")
+	assert.Contains(t, web.Handle(u, &http.Request{}), "n4,auth,Service: testapp")
+
+	// a failing middleware after the counter keeps its options unchanged
+	fr.options["testapp"].Middlewares[0].Options = map[string]string{"label": "m"}
+	fr.options["testapp"].Middlewares[1].Name = "unknown"
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, interfaces.ErrUnknownMiddleware, p.frontendErrors["testapp"])
+	assert.Contains(t, web.Handle(u, &http.Request{}), "n5,auth,Service: testapp")
+
+	// as do failing route installations
+	fr.options["testapp"].Middlewares[1].Name = "auth"
+	web.FailAll = true
+
+	p.configureFrontend("testapp")
+
+	web.FailAll = false
+
+	assert.Contains(t, web.Handle(u, &http.Request{}), "n6,auth,Service: testapp")
+
+	p.configureFrontend("testapp")
+
+	assert.NotContains(t, p.frontendErrors, "testapp")
+	assert.Contains(t, web.Handle(u, &http.Request{}), "m7,auth,Service: testapp")
+}
+
+func TestUpsertFrontendShouldKeepRateLimitOnFailedUpdate(t *testing.T) {
+	web := &dummyWebServer{}
+	p, fr := newMiddlewareTestProxy(web, "rateLimit", "auth")
+	p.middlewareRegistry = middleware.NewRegistry()
+
+	limit := fr.options["testapp"].Middlewares[0]
+	limit.Options = map[string]string{"rate": "0.001", "burst": "1", "key": "global"}
+
+	fr.options["testapp"].Middlewares[1].Options = map[string]string{"X-Auth": "yes"}
+	fr.options["testapp"].Middlewares[1].Name = "requestHeaders"
+
+	p.configureFrontend("testapp")
+
+	u, _ := url.Parse("http://testapp")
+	assert.Equal(t, "service", web.Handle(u, &http.Request{}))
+
+	// raising the burst fails because of the unknown middleware
+	limit.Options = map[string]string{"rate": "0.001", "burst": "10", "key": "global"}
+	fr.options["testapp"].Middlewares[1].Name = "unknown"
+
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, interfaces.ErrUnknownMiddleware, p.frontendErrors["testapp"])
+	assert.Contains(t, web.Handle(u, &http.Request{}), "Too Many Requests")
+}
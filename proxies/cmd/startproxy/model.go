@@ -2,6 +2,7 @@ package startproxy
 
 import (
 	"context"
+	"net/http"
 	"sync"
 	"time"
 
@@ -31,6 +32,36 @@ type Model struct {
 	// requests.
 	LoadBalancer interfaces.LoadBalancer
 
+	// CertificateManager specifies the certificate manager used for
+	// frontends with automatic TLS. It is optional; without it such frontends
+	// are served over HTTP and report an error.
+	CertificateManager interfaces.CertificateManager
+
+	// CertificateStore specifies the store used to persist the certificates
+	// of frontends. Stored certificates are installed when the proxy starts,
+	// before the frontends are configured. It is optional.
+	CertificateStore interfaces.CertificateStore
+
+	// MiddlewareRegistry creates the middlewares declared by the frontend
+	// options. It is optional, but required for frontends declaring
+	// middlewares.
+	MiddlewareRegistry interfaces.MiddlewareRegistry
+
+	// ExpiryThresholds specifies the remaining validities at which a warning
+	// is logged for a certificate installed on the secure web server.
+	// Defaults to 30, 14, 7 and 1 days. Expiry monitoring requires a secure
+	// web server implementing interfaces.CertificateInspector.
+	ExpiryThresholds []time.Duration
+
+	// ExpiryCheckInterval defines the frequency at which the expiry of the
+	// installed certificates is checked. Defaults to 1 hour.
+	ExpiryCheckInterval time.Duration
+
+	// MaintenanceHandler is optional. If set, it serves all requests for
+	// secure frontends whose certificate has expired, over both HTTP and
+	// HTTPS, instead of redirecting clients to an expired certificate.
+	MaintenanceHandler http.Handler
+
 	// PollingDuration defines the frequency at which the complete configuration
 	// of the proxy is refreshed. This can be used when watchers are not
 	// available, or when watchers are not reliable (i.e. change events could be
@@ -4,16 +4,25 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"reflect"
 	"sync"
 	"time"
 
 	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+	"github.com/off-sync/platform-proxy-domain/services"
 )
 
 type proxy struct {
 	// context
-	ctx context.Context
-	wg  *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+
+	// lifecycle: ready is closed after the first configuration, done is
+	// closed when the proxy has returned
+	ready chan struct{}
+	done  chan struct{}
 
 	// logging
 	logger interfaces.Logger
@@ -28,19 +37,59 @@ type proxy struct {
 	secureWebServer interfaces.SecureWebServer
 	loadBalancer    interfaces.LoadBalancer
 
-	// internal state
+	// certificateManager is optional, it is required for frontends with
+	// automatic TLS
+	certificateManager interfaces.CertificateManager
+
+	// certificateStore is optional, it persists the certificates of
+	// frontends
+	certificateStore interfaces.CertificateStore
+
+	// middlewareRegistry creates the middlewares declared by frontends
+	middlewareRegistry interfaces.MiddlewareRegistry
+
+	// certificate expiry monitoring
+	expiryThresholds    []time.Duration
+	expiryCheckInterval time.Duration
+	maintenanceHandler  http.Handler
+
+	// internal state, guarded by mu
+	mu              sync.RWMutex
 	serviceHandlers map[string]http.Handler
 	frontendConfigs map[string]*frontendConfig
+
+	// last revisions received through watcher events
+	serviceRevisions  map[string]uint64
+	frontendRevisions map[string]uint64
+
+	// last errors encountered configuring services and frontends
+	serviceErrors  map[string]error
+	frontendErrors map[string]error
+
+	// expiry of the installed certificates at the last check
+	expiryStates map[string]*certificateExpiry
 }
 
 type frontendConfig struct {
-	serviceName string
+	serviceName        string
+	serviceHandlerKind interfaces.ServiceHandlerKind
+	url                *url.URL
+	isSecure           bool
 
-	// url is needed for deleting the configured routes from the web servers
-	url *url.URL
+	// maintenance is true if the routes serve the maintenance handler, as
+	// the certificate has expired
+	maintenance bool
 
-	// isSecure is needed for deleting the configured routes from the web servers
-	isSecure bool
+	// middlewares are the middlewares composed around the service handler
+	middlewares []*frontendMiddleware
+
+	// managedDomain is the domain name for which a certificate is managed by
+	// the certificate manager, or empty if none
+	managedDomain string
+
+	// routes is needed for deleting or replacing the configured routes on the
+	// web servers
+	routes []*route
 }
 
 func newProxy(
@@ -54,9 +103,14 @@ func newProxy(
 	secureWebServer interfaces.SecureWebServer,
 	loadBalancer interfaces.LoadBalancer) *proxy {
 
+	ctx, cancel := context.WithCancel(ctx)
+
 	return &proxy{
 		ctx:                ctx,
+		cancel:             cancel,
 		wg:                 wg,
+		ready:              make(chan struct{}),
+		done:               make(chan struct{}),
 		logger:             logger,
 		serviceRepository:  serviceRepository,
 		frontendRepository: frontendRepository,
@@ -66,12 +120,29 @@ func newProxy(
 		loadBalancer:       loadBalancer,
 		serviceHandlers:    make(map[string]http.Handler),
 		frontendConfigs:    make(map[string]*frontendConfig),
+		serviceRevisions:   make(map[string]uint64),
+		frontendRevisions:  make(map[string]uint64),
+		serviceErrors:      make(map[string]error),
+		frontendErrors:     make(map[string]error),
+
+		expiryStates: make(map[string]*certificateExpiry),
 	}
 }
 
+// run configures the proxy and keeps it up to date until its context is
+// done. The caller must have added the proxy to its wait group.
 func (p *proxy) run() {
-	// configure all services and frontends
+	defer p.wg.Done()
+	defer close(p.done)
+
+	// install stored certificates, then configure all services and frontends
+	p.mu.Lock()
+	p.restoreCertificates()
 	p.configure()
+	p.checkCertificates()
+	p.mu.Unlock()
+
+	close(p.ready)
 
 	// subscribe to service events
 	serviceEvents := make(<-chan interfaces.ServiceEvent)
@@ -80,6 +151,7 @@ func (p *proxy) run() {
 		p.logger.Info("subscribing to service watcher")
 
 		serviceEvents = w.Subscribe()
+		defer w.Unsubscribe(serviceEvents)
 	}
 
 	// subscribe to frontend events
@@ -89,19 +161,27 @@ func (p *proxy) run() {
 		p.logger.Info("subscribing to frontend watcher")
 
 		frontendEvents = w.Subscribe()
+		defer w.Unsubscribe(frontendEvents)
 	}
 
 	// create polling ticker
 	pollTicker := time.NewTicker(p.pollingDuration)
 
-	p.wg.Add(1)
+	// create expiry ticker, if expiry monitoring is configured
+	var expiryTicks <-chan time.Time
+
+	if p.expiryCheckInterval > 0 {
+		expiryTicker := time.NewTicker(p.expiryCheckInterval)
+		defer expiryTicker.Stop()
+
+		expiryTicks = expiryTicker.C
+	}
 
 	for {
 		select {
 		// respond to the context closing
 		case <-p.ctx.Done():
 			pollTicker.Stop()
-			p.wg.Done()
 
 			p.logger.Info("context is done: returning")
 			return
@@ -109,16 +189,34 @@ func (p *proxy) run() {
 			// respond to polling events
 		case <-pollTicker.C:
 			p.logger.Info("polling configuration")
+
+			p.mu.Lock()
 			p.configure()
+			p.mu.Unlock()
+
+			break
+
+			// respond to expiry check events
+		case <-expiryTicks:
+			p.logger.Debug("checking certificate expiry")
+
+			p.mu.Lock()
+			p.checkCertificates()
+			p.mu.Unlock()
+
 			break
 
 			// respond to service events
 		case serviceEvent := <-serviceEvents:
 			p.logger.
 				WithField("name", serviceEvent.Name).
+				WithField("kind", serviceEvent.Kind).
+				WithField("revision", serviceEvent.Revision).
 				Info("received service event")
 
-			p.configureService(serviceEvent.Name)
+			p.mu.Lock()
+			p.handleServiceEvent(serviceEvent)
+			p.mu.Unlock()
 
 			break
 
@@ -126,9 +224,13 @@ func (p *proxy) run() {
 		case frontendEvent := <-frontendEvents:
 			p.logger.
 				WithField("name", frontendEvent.Name).
+				WithField("kind", frontendEvent.Kind).
+				WithField("revision", frontendEvent.Revision).
 				Info("received frontend event")
 
-			p.configureFrontend(frontendEvent.Name)
+			p.mu.Lock()
+			p.handleFrontendEvent(frontendEvent)
+			p.mu.Unlock()
 
 			break
 		}
@@ -137,37 +239,159 @@ func (p *proxy) run() {
 
 func (p *proxy) configure() {
 	// configure services first to create the required handlers
-	services, err := p.serviceRepository.ListServices()
+	serviceNames, err := p.serviceRepository.ListServices()
 	if err != nil {
 		p.logger.
 			WithError(err).
 			Error("listing services")
 	} else {
-		for _, service := range services {
-			p.configureService(service)
+		p.deleteStaleServices(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			p.configureService(serviceName)
 		}
 	}
 
 	// configure frontends
-	frontends, err := p.frontendRepository.ListFrontends()
+	frontendNames, err := p.frontendRepository.ListFrontends()
 	if err != nil {
 		p.logger.
 			WithError(err).
 			Error("listing frontends")
 	} else {
-		for _, frontend := range frontends {
-			p.configureFrontend(frontend)
+		p.deleteStaleFrontends(frontendNames)
+
+		for _, frontendName := range frontendNames {
+			p.configureFrontend(frontendName)
 		}
 	}
 }
 
-func (p *proxy) getServiceHandler(serviceName string) http.Handler {
+// deleteStaleServices deletes all configured services that are not contained
+// in the provided service names.
+func (p *proxy) deleteStaleServices(serviceNames []string) {
+	listed := make(map[string]bool, len(serviceNames))
+	for _, serviceName := range serviceNames {
+		listed[serviceName] = true
+	}
+
+	for serviceName := range p.serviceHandlers {
+		if listed[serviceName] {
+			continue
+		}
+
+		p.logger.
+			WithField("name", serviceName).
+			Info("service no longer listed")
+
+		p.deleteService(serviceName)
+	}
+
+	// forget the errors of services that failed to configure
+	for serviceName := range p.serviceErrors {
+		if !listed[serviceName] {
+			delete(p.serviceErrors, serviceName)
+		}
+	}
+}
+
+// deleteStaleFrontends deletes all configured frontends that are not contained
+// in the provided frontend names.
+func (p *proxy) deleteStaleFrontends(frontendNames []string) {
+	listed := make(map[string]bool, len(frontendNames))
+	for _, frontendName := range frontendNames {
+		listed[frontendName] = true
+	}
+
+	for frontendName := range p.frontendConfigs {
+		if listed[frontendName] {
+			continue
+		}
+
+		p.logger.
+			WithField("name", frontendName).
+			Info("frontend no longer listed")
+
+		p.deleteFrontend(frontendName)
+	}
+
+	// forget the errors of frontends that failed to configure
+	for frontendName := range p.frontendErrors {
+		if !listed[frontendName] {
+			delete(p.frontendErrors, frontendName)
+		}
+	}
+}
+
+func (p *proxy) handleServiceEvent(event interfaces.ServiceEvent) {
+	if event.Revision > 0 {
+		// ignore events that are older than the last one received
+		if event.Revision <= p.serviceRevisions[event.Name] {
+			p.logger.
+				WithField("name", event.Name).
+				WithField("revision", event.Revision).
+				Debug("ignoring out-of-order service event")
+
+			return
+		}
+
+		p.serviceRevisions[event.Name] = event.Revision
+	}
+
+	switch {
+	case event.Kind == interfaces.EventDeleted:
+		p.deleteService(event.Name)
+	case event.Service != nil:
+		p.upsertService(event.Service)
+	default:
+		p.configureService(event.Name)
+	}
+}
+
+func (p *proxy) handleFrontendEvent(event interfaces.FrontendEvent) {
+	if event.Revision > 0 {
+		// ignore events that are older than the last one received
+		if event.Revision <= p.frontendRevisions[event.Name] {
+			p.logger.
+				WithField("name", event.Name).
+				WithField("revision", event.Revision).
+				Debug("ignoring out-of-order frontend event")
+
+			return
+		}
+
+		p.frontendRevisions[event.Name] = event.Revision
+	}
+
+	switch {
+	case event.Kind == interfaces.EventDeleted:
+		p.deleteFrontend(event.Name)
+	case event.Frontend != nil:
+		p.upsertFrontend(event.Frontend)
+	default:
+		p.configureFrontend(event.Name)
+	}
+}
+
+func (p *proxy) getServiceHandler(serviceName string) (http.Handler, interfaces.ServiceHandlerKind) {
 	handler, found := p.serviceHandlers[serviceName]
 	if !found {
-		return http.NotFoundHandler()
+		return http.NotFoundHandler(), interfaces.ServiceHandlerNotFound
+	}
+
+	if _, ok := handler.(serviceErrorHandler); ok {
+		return handler, interfaces.ServiceHandlerError
 	}
 
-	return handler
+	return handler, interfaces.ServiceHandlerLoadBalancer
+}
+
+// serviceErrorHandler is used for services that could not be configured on
+// the load balancer. It returns an internal server error on each request.
+type serviceErrorHandler struct{}
+
+func (h serviceErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "Service not configured", http.StatusInternalServerError)
 }
 
 func (p *proxy) configureService(name string) {
@@ -176,29 +400,7 @@ func (p *proxy) configureService(name string) {
 	if err != nil {
 		// check if error means that service does not exists
 		if err == interfaces.ErrUnknownService {
-			// check if service was configured previously
-			if _, found := p.serviceHandlers[name]; !found {
-				return
-			}
-
-			p.logger.
-				WithField("name", name).
-				Debug("deleting service")
-
-			// delete service handler mapping
-			delete(p.serviceHandlers, name)
-
-			// reconfigure linked frontends
-			for frontendName, frontendConfig := range p.frontendConfigs {
-				if frontendConfig.serviceName != name {
-					continue
-				}
-
-				p.configureFrontend(frontendName)
-			}
-
-			// delete load balancer service
-			p.loadBalancer.DeleteService(name)
+			p.deleteService(name)
 
 			return
 		}
@@ -208,31 +410,119 @@ func (p *proxy) configureService(name string) {
 			WithField("name", name).
 			Error("describing service")
 
+		p.serviceErrors[name] = err
+
+		return
+	}
+
+	p.upsertService(service)
+}
+
+func (p *proxy) deleteService(name string) {
+	// a service that failed to configure only has an error
+	delete(p.serviceErrors, name)
+
+	// check if service was configured previously
+	if _, found := p.serviceHandlers[name]; !found {
 		return
 	}
 
+	p.logger.
+		WithField("name", name).
+		Debug("deleting service")
+
+	// delete service handler mapping
+	delete(p.serviceHandlers, name)
+
+	// reconfigure linked frontends
+	for frontendName, frontendConfig := range p.frontendConfigs {
+		if frontendConfig.serviceName != name {
+			continue
+		}
+
+		p.configureFrontend(frontendName)
+	}
+
+	// delete load balancer service
+	p.loadBalancer.DeleteService(name)
+}
+
+func (p *proxy) upsertService(service *services.Service) {
 	p.logger.
 		WithField("name", service.Name).
 		WithField("servers", service.Servers).
 		Debug("upserting service")
 
-	handler, err := p.loadBalancer.UpsertService(service.Name, service.Servers...)
+	handler, err := p.upsertLoadBalancerService(service)
 	if err != nil {
 		p.logger.
 			WithError(err).
-			WithField("name", name).
+			WithField("name", service.Name).
 			WithField("servers", service.Servers).
 			Error("upserting service")
 
 		// set the service handler to return an internal server error on each
 		// request
-		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, "Service not configured", http.StatusInternalServerError)
-		})
+		handler = serviceErrorHandler{}
+
+		p.serviceErrors[service.Name] = err
+	} else {
+		delete(p.serviceErrors, service.Name)
 	}
 
+	previous, found := p.serviceHandlers[service.Name]
+
 	// upsert service handler mapping
 	p.serviceHandlers[service.Name] = handler
+
+	if found && sameHandler(previous, handler) {
+		return
+	}
+
+	// reconfigure linked frontends, so their routes use the new handler
+	for frontendName, frontendConfig := range p.frontendConfigs {
+		if frontendConfig.serviceName != service.Name {
+			continue
+		}
+
+		p.configureFrontend(frontendName)
+	}
+}
+
+// sameHandler returns true if a and b are known to be the same handler.
+// Handlers that cannot be compared, such as functions, are never the same.
+func sameHandler(a, b http.Handler) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// upsertLoadBalancerService upserts the service on the load balancer. The
+// service options are passed along if both the service repository and the
+// load balancer support them.
+func (p *proxy) upsertLoadBalancerService(service *services.Service) (http.Handler, error) {
+	lb, ok := p.loadBalancer.(interfaces.ConfigurableLoadBalancer)
+	if !ok {
+		return p.loadBalancer.UpsertService(service.Name, service.Servers...)
+	}
+
+	r, ok := p.serviceRepository.(interfaces.ServiceOptionsRepository)
+	if !ok {
+		return p.loadBalancer.UpsertService(service.Name, service.Servers...)
+	}
+
+	options, err := r.DescribeServiceOptions(service.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return lb.UpsertServiceWithOptions(service.Name, options, service.Servers...)
 }
 
 func (p *proxy) configureFrontend(name string) {
@@ -241,31 +531,7 @@ func (p *proxy) configureFrontend(name string) {
 	if err != nil {
 		// check if error means that frontend does not exist
 		if err == interfaces.ErrUnknownFrontend {
-			// check if frontend was configured previously
-			frontendConfig, found := p.frontendConfigs[name]
-			if !found {
-				return
-			}
-
-			p.logger.
-				WithField("name", name).
-				Debug("deleting frontend")
-
-			// delete frontend config
-			delete(p.frontendConfigs, name)
-
-			// delete web server routes
-			if frontendConfig.isSecure {
-				p.secureWebServer.DeleteRoute(frontendConfig.url)
-
-				httpURL := &url.URL{}
-				*httpURL = *frontendConfig.url
-				httpURL.Scheme = "http"
-
-				p.webServer.DeleteRoute(httpURL)
-			} else {
-				p.webServer.DeleteRoute(frontendConfig.url)
-			}
+			p.deleteFrontend(name)
 
 			return
 		}
@@ -275,69 +541,233 @@ func (p *proxy) configureFrontend(name string) {
 			WithField("name", name).
 			Error("describing frontend")
 
+		p.frontendErrors[name] = err
+
+		return
+	}
+
+	p.upsertFrontend(frontend)
+}
+
+func (p *proxy) deleteFrontend(name string) {
+	// a frontend that failed to configure only has an error
+	delete(p.frontendErrors, name)
+
+	// check if frontend was configured previously
+	frontendConfig, found := p.frontendConfigs[name]
+	if !found {
 		return
 	}
 
+	p.logger.
+		WithField("name", name).
+		Debug("deleting frontend")
+
+	// delete frontend config
+	delete(p.frontendConfigs, name)
+
+	// delete web server routes
+	for _, route := range frontendConfig.routes {
+		p.deleteRoute(route, frontendConfig)
+	}
+
+	if frontendConfig.managedDomain != "" {
+		p.certificateManager.UnmanageCertificate(frontendConfig.managedDomain)
+	}
+}
+
+func (p *proxy) upsertFrontend(frontend *frontends.Frontend) {
 	p.logger.
 		WithField("name", frontend.Name).
 		WithField("url", frontend.URL).
 		WithField("service_name", frontend.ServiceName).
 		Debug("configuring frontend")
 
-	if frontend.Certificate != nil {
+	options, err := p.describeFrontendOptions(frontend.Name)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", frontend.Name).
+			Error("describing frontend options")
+
+		p.frontendErrors[frontend.Name] = err
+
+		return
+	}
+
+	serviceHandler, serviceHandlerKind := p.getServiceHandler(frontend.ServiceName)
+
+	previous := p.frontendConfigs[frontend.Name]
+
+	handler, middlewares, err := p.frontendHandler(options, serviceHandler, previous)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", frontend.Name).
+			Error("composing frontend handler")
+
+		p.frontendErrors[frontend.Name] = err
+
+		return
+	}
+
+	// the handler used for HTTPS, adding the security headers
+	var securityHeaders *interfaces.SecurityHeaders
+	if options != nil {
+		securityHeaders = options.SecurityHeaders
+	}
+
+	secureHandler, err := newSecurityHeadersHandler(securityHeaders, handler)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", frontend.Name).
+			Error("configuring security headers")
+
+		p.frontendErrors[frontend.Name] = err
+
+		return
+	}
+
+	config := &frontendConfig{
+		serviceName:        frontend.ServiceName,
+		serviceHandlerKind: serviceHandlerKind,
+		url:                frontend.URL,
+		isSecure:           frontend.Certificate != nil,
+		middlewares:        middlewares,
+	}
+
+	// keeps track of errors that do not prevent installing the routes
+	var lastErr error
+
+	autoTLS := frontend.Certificate == nil && options != nil && options.AutoTLS
+	if autoTLS && p.certificateManager == nil {
+		p.logger.
+			WithError(ErrCertificateManagerMissing).
+			WithField("name", frontend.Name).
+			Error("configuring automatic TLS")
+
+		lastErr = ErrCertificateManagerMissing
+		autoTLS = false
+	}
+
+	switch {
+	case frontend.Certificate != nil:
 		// configure HTTPS
-		err := p.secureWebServer.UpsertCertificate(
-			frontend.URL.Host,
-			frontend.Certificate)
+		err := p.installCertificate(frontend)
 		if err != nil {
-			p.logger.
-				WithError(err).
-				WithField("host", frontend.URL.Host).
-				Error("upserting certificate")
+			lastErr = err
 		}
 
-		err = p.secureWebServer.UpsertRoute(
-			frontend.URL,
-			p.getServiceHandler(frontend.ServiceName))
+		redirect, err := p.frontendRedirect(frontend.Name, frontend.URL, options, serviceHandler)
 		if err != nil {
-			p.logger.
-				WithError(err).
-				WithField("url", frontend.URL).
-				Error("upserting route")
+			lastErr = err
 		}
 
-		// configure HTTP redirect
-		httpURL := &url.URL{}
-		*httpURL = *frontend.URL
-		httpURL.Scheme = "http"
+		p.addSecureRoutes(config, frontend.URL, secureHandler, redirect)
+	case autoTLS:
+		// configure HTTPS, the certificate is installed by the certificate
+		// manager once obtained
+		secureURL := httpsURL(frontend.URL)
 
-		err = p.webServer.UpsertRoute(httpURL,
-			http.RedirectHandler(
-				frontend.URL.String(),
-				http.StatusMovedPermanently))
+		config.isSecure = true
+		config.managedDomain = frontend.URL.Hostname()
+
+		redirect, err := p.frontendRedirect(frontend.Name, secureURL, options, serviceHandler)
 		if err != nil {
-			p.logger.
-				WithError(err).
-				WithField("url", frontend.URL).
-				Error("upserting route")
+			lastErr = err
 		}
-	} else {
+
+		p.addSecureRoutes(config, secureURL, secureHandler, redirect)
+
+		// the challenges are not redirected
+		config.addChallenge(challengeURL(frontend.URL),
+			p.certificateManager.ChallengeHandler())
+	default:
 		// configure HTTP
-		err := p.webServer.UpsertRoute(
-			frontend.URL,
-			p.getServiceHandler(frontend.ServiceName))
+		config.addRoute(false, frontend.URL, handler)
+	}
+
+	// replace the routes of the previous config, if any
+	err = p.installRoutes(config, previous)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", frontend.Name).
+			Error("configuring frontend")
+
+		p.frontendErrors[frontend.Name] = err
+
+		return
+	}
+
+	err = reconfigureMiddlewares(config.middlewares)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", frontend.Name).
+			Error("reconfiguring middlewares")
+
+		lastErr = err
+	}
+
+	err = p.manageCertificate(config, previous)
+	if err != nil {
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		p.frontendErrors[frontend.Name] = lastErr
+	} else {
+		delete(p.frontendErrors, frontend.Name)
+	}
+
+	// upsert frontend config
+	p.frontendConfigs[frontend.Name] = config
+}
+
+// describeFrontendOptions returns the options of the frontend, or nil if the
+// frontend repository does not support them.
+func (p *proxy) describeFrontendOptions(name string) (*interfaces.FrontendOptions, error) {
+	r, ok := p.frontendRepository.(interfaces.FrontendOptionsRepository)
+	if !ok {
+		return nil, nil
+	}
+
+	return r.DescribeFrontendOptions(name)
+}
+
+// manageCertificate hands the managed domain of config over to the
+// certificate manager, and releases the managed domain of the previous
+// config. If the certificate manager fails, the managed domain of config is
+// cleared.
+func (p *proxy) manageCertificate(config, previous *frontendConfig) error {
+	var previousDomain string
+	if previous != nil {
+		previousDomain = previous.managedDomain
+	}
+
+	if config.managedDomain == previousDomain {
+		return nil
+	}
+
+	var err error
+
+	if config.managedDomain != "" {
+		err = p.certificateManager.ManageCertificate(config.managedDomain)
 		if err != nil {
 			p.logger.
 				WithError(err).
-				WithField("url", frontend.URL).
-				Error("upserting route")
+				WithField("domain_name", config.managedDomain).
+				Error("managing certificate")
+
+			config.managedDomain = ""
 		}
 	}
 
-	// upsert service handler mapping
-	p.frontendConfigs[name] = &frontendConfig{
-		serviceName: frontend.ServiceName,
-		url:         frontend.URL,
-		isSecure:    frontend.Certificate != nil,
+	if previousDomain != "" {
+		p.certificateManager.UnmanageCertificate(previousDomain)
 	}
+
+	return err
 }
@@ -1 +1,454 @@
 package startproxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+func newTestProxy(
+	sr interfaces.ServiceRepository,
+	fr interfaces.FrontendRepository,
+	web *dummyWebServer) *proxy {
+	return newProxy(
+		context.Background(),
+		&sync.WaitGroup{},
+		logger,
+		sr,
+		fr,
+		time.Minute,
+		web,
+		web,
+		&dummyLoadBalancer{})
+}
+
+func TestHandleServiceEventShouldUsePayload(t *testing.T) {
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		&dummyWebServer{})
+
+	// the repository does not know this service, so describing it would
+	// not result in a handler
+	p.handleServiceEvent(interfaces.ServiceEvent{
+		Name:    "payload",
+		Kind:    interfaces.EventCreated,
+		Service: mockService("payload"),
+	})
+
+	assert.Contains(t, p.serviceHandlers, "payload")
+}
+
+func TestHandleServiceEventShouldDeleteService(t *testing.T) {
+	p := newTestProxy(
+		&dummyServiceRepository{serviceNames: []string{"testapp"}},
+		&dummyFrontendRepository{},
+		&dummyWebServer{})
+
+	p.configureService("testapp")
+	assert.Contains(t, p.serviceHandlers, "testapp")
+
+	// the repository still knows this service, but the event is authoritative
+	p.handleServiceEvent(interfaces.ServiceEvent{
+		Name: "testapp",
+		Kind: interfaces.EventDeleted,
+	})
+
+	assert.NotContains(t, p.serviceHandlers, "testapp")
+}
+
+func TestHandleServiceEventShouldIgnoreOutOfOrderEvents(t *testing.T) {
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		&dummyWebServer{})
+
+	p.handleServiceEvent(interfaces.ServiceEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventDeleted,
+		Revision: 2,
+	})
+
+	p.handleServiceEvent(interfaces.ServiceEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Revision: 1,
+		Service:  mockService("payload"),
+	})
+
+	assert.NotContains(t, p.serviceHandlers, "payload")
+
+	p.handleServiceEvent(interfaces.ServiceEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Revision: 3,
+		Service:  mockService("payload"),
+	})
+
+	assert.Contains(t, p.serviceHandlers, "payload")
+}
+
+func TestHandleFrontendEventShouldUsePayload(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.handleFrontendEvent(interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Frontend: mockFrontend("payload"),
+	})
+
+	assert.Contains(t, p.frontendConfigs, "payload")
+	assert.Contains(t, web.routes, "http://payload")
+}
+
+func TestHandleFrontendEventShouldIgnoreOutOfOrderEvents(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.handleFrontendEvent(interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Revision: 5,
+		Frontend: mockFrontend("payload"),
+	})
+
+	p.handleFrontendEvent(interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventDeleted,
+		Revision: 4,
+	})
+
+	assert.Contains(t, p.frontendConfigs, "payload")
+
+	p.handleFrontendEvent(interfaces.FrontendEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventDeleted,
+		Revision: 6,
+	})
+
+	assert.NotContains(t, p.frontendConfigs, "payload")
+
+	u, _ := url.Parse("http://payload")
+	assert.Contains(t, web.Handle(u, &http.Request{}), "Not found")
+}
+
+func TestConfigureShouldDeleteUnlistedServicesAndFrontends(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp", "secure-testapp"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp", "secure-testapp"}}
+	web := &dummyWebServer{}
+	lb := &dummyLoadBalancer{}
+
+	p := newProxy(
+		context.Background(),
+		&sync.WaitGroup{},
+		logger,
+		sr,
+		fr,
+		time.Minute,
+		web,
+		web,
+		lb)
+
+	p.configure()
+
+	assert.Len(t, p.serviceHandlers, 2)
+	assert.Len(t, p.frontendConfigs, 2)
+	assert.Contains(t, web.routes, "https://secure-testapp")
+	assert.Contains(t, web.routes, "http://secure-testapp")
+
+	// silently remove entities from the repositories
+	sr.serviceNames = []string{"testapp"}
+	fr.frontendNames = []string{"testapp"}
+
+	p.configure()
+
+	assert.Len(t, p.serviceHandlers, 1)
+	assert.Contains(t, p.serviceHandlers, "testapp")
+	assert.Equal(t, []string{"secure-testapp"}, lb.deleted)
+
+	assert.Len(t, p.frontendConfigs, 1)
+	assert.Contains(t, p.frontendConfigs, "testapp")
+	assert.NotContains(t, web.routes, "https://secure-testapp")
+	assert.NotContains(t, web.routes, "http://secure-testapp")
+	assert.Contains(t, web.routes, "http://testapp")
+}
+
+func TestConfigureShouldKeepStateWhenListingFails(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp"}}
+	web := &dummyWebServer{}
+
+	p := newTestProxy(sr, fr, web)
+
+	p.configure()
+
+	// the dummy repositories return an error when empty
+	sr.serviceNames = nil
+	fr.frontendNames = nil
+
+	p.configure()
+
+	assert.Contains(t, p.serviceHandlers, "testapp")
+	assert.Contains(t, p.frontendConfigs, "testapp")
+}
+
+type optionsServiceRepository struct {
+	dummyServiceRepository
+	options map[string]*interfaces.ServiceOptions
+}
+
+func (r *optionsServiceRepository) DescribeServiceOptions(name string) (*interfaces.ServiceOptions, error) {
+	if _, err := r.DescribeService(name); err != nil {
+		return nil, err
+	}
+
+	return r.options[name], nil
+}
+
+type optionsLoadBalancer struct {
+	dummyLoadBalancer
+	options map[string]*interfaces.ServiceOptions
+}
+
+func (lb *optionsLoadBalancer) UpsertServiceWithOptions(
+	name string,
+	options *interfaces.ServiceOptions,
+	urls ...*url.URL) (http.Handler, error) {
+	lb.options[name] = options
+
+	return lb.UpsertService(name, urls...)
+}
+
+func TestUpsertServiceShouldPassServiceOptions(t *testing.T) {
+	options := &interfaces.ServiceOptions{
+		HealthCheck: &interfaces.HealthCheck{Path: "/healthz"},
+	}
+
+	sr := &optionsServiceRepository{
+		dummyServiceRepository: dummyServiceRepository{serviceNames: []string{"testapp"}},
+		options:                map[string]*interfaces.ServiceOptions{"testapp": options},
+	}
+	lb := &optionsLoadBalancer{options: make(map[string]*interfaces.ServiceOptions)}
+
+	p := newProxy(
+		context.Background(),
+		&sync.WaitGroup{},
+		logger,
+		sr,
+		&dummyFrontendRepository{},
+		time.Minute,
+		&dummyWebServer{},
+		&dummyWebServer{},
+		lb)
+
+	p.configureService("testapp")
+
+	assert.Contains(t, p.serviceHandlers, "testapp")
+	assert.True(t, options == lb.options["testapp"])
+}
+
+type optionsFrontendRepository struct {
+	dummyFrontendRepository
+	options map[string]*interfaces.FrontendOptions
+}
+
+func (r *optionsFrontendRepository) DescribeFrontendOptions(name string) (*interfaces.FrontendOptions, error) {
+	if _, err := r.DescribeFrontend(name); err != nil {
+		return nil, err
+	}
+
+	return r.options[name], nil
+}
+
+func newAutoTLSTestProxy(web *dummyWebServer, cm *dummyCertificateManager) *proxy {
+	fr := &optionsFrontendRepository{
+		dummyFrontendRepository: dummyFrontendRepository{frontendNames: []string{"testapp"}},
+		options: map[string]*interfaces.FrontendOptions{
+			"testapp": {AutoTLS: true},
+		},
+	}
+
+	p := newTestProxy(&dummyServiceRepository{}, fr, web)
+	p.serviceHandlers["testapp"] = namedHandler("service")
+
+	if cm != nil {
+		p.certificateManager = cm
+	}
+
+	return p
+}
+
+func TestUpsertFrontendShouldManageCertificateForAutoTLS(t *testing.T) {
+	web := &dummyWebServer{}
+	cm := &dummyCertificateManager{}
+	p := newAutoTLSTestProxy(web, cm)
+
+	p.configureFrontend("testapp")
+
+	assert.True(t, p.frontendConfigs["testapp"].isSecure)
+	assert.Equal(t, map[string]int{"testapp": 1}, cm.managed)
+	assert.NotContains(t, p.frontendErrors, "testapp")
+
+	u, _ := url.Parse("https://testapp")
+	assert.Equal(t, "service", web.Handle(u, &http.Request{}))
+
+	u, _ = url.Parse("http://testapp/.well-known/acme-challenge")
+	assert.Equal(t, "challenge", web.Handle(u, &http.Request{}))
+
+	assert.Contains(t, web.routes, "http://testapp")
+
+	// reconfiguring must not manage the certificate twice
+	p.configureFrontend("testapp")
+
+	assert.Equal(t, map[string]int{"testapp": 1}, cm.managed)
+
+	p.deleteFrontend("testapp")
+
+	assert.Empty(t, cm.managed)
+	assert.Empty(t, web.routes)
+}
+
+func TestUpsertFrontendShouldReportMissingCertificateManager(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newAutoTLSTestProxy(web, nil)
+
+	p.configureFrontend("testapp")
+
+	assert.False(t, p.frontendConfigs["testapp"].isSecure)
+	assert.Equal(t, ErrCertificateManagerMissing, p.frontendErrors["testapp"])
+	assert.Contains(t, web.routes, "http://testapp")
+}
+
+func TestUpsertFrontendShouldReportCertificateManagerErrors(t *testing.T) {
+	web := &dummyWebServer{}
+	cm := &dummyCertificateManager{FailAll: true}
+	p := newAutoTLSTestProxy(web, cm)
+
+	p.configureFrontend("testapp")
+
+	assert.Error(t, p.frontendErrors["testapp"])
+	assert.Empty(t, p.frontendConfigs["testapp"].managedDomain)
+
+	// retried on the next configuration
+	cm.FailAll = false
+
+	p.configureFrontend("testapp")
+
+	assert.NotContains(t, p.frontendErrors, "testapp")
+	assert.Equal(t, map[string]int{"testapp": 1}, cm.managed)
+}
+
+func TestRestoreCertificatesShouldInstallUnexpiredCertificatesOfFrontends(t *testing.T) {
+	valid := &frontends.Certificate{Certificate: []byte("valid")}
+
+	cs := &dummyCertificateStore{
+		certs: map[string]*interfaces.StoredCertificate{
+			"valid": {
+				DomainName:  "valid",
+				Certificate: valid,
+				NotAfter:    time.Now().Add(time.Hour),
+			},
+			"expired": {
+				DomainName:  "expired",
+				Certificate: &frontends.Certificate{},
+				NotAfter:    time.Now().Add(-time.Hour),
+			},
+			"unused": {
+				DomainName:  "unused",
+				Certificate: &frontends.Certificate{},
+				NotAfter:    time.Now().Add(time.Hour),
+			},
+		},
+	}
+
+	web := &dummyWebServer{}
+	fr := &dummyFrontendRepository{frontendNames: []string{"valid", "expired"}}
+	p := newTestProxy(&dummyServiceRepository{}, fr, web)
+	p.certificateStore = cs
+
+	p.restoreCertificates()
+
+	assert.Equal(t, map[string]*frontends.Certificate{"valid": valid}, web.certs)
+}
+
+func TestUpsertFrontendShouldStoreCertificate(t *testing.T) {
+	cs := &dummyCertificateStore{}
+
+	web := &dummyWebServer{}
+	p := newTestProxy(&dummyServiceRepository{}, &dummyFrontendRepository{}, web)
+	p.certificateStore = cs
+
+	cert := validCertificate("testapp")
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("https://testapp", cert)))
+
+	if assert.Contains(t, cs.certs, "testapp") {
+		assert.Equal(t, cert, cs.certs["testapp"].Certificate)
+	}
+
+	// failing to store the certificate does not fail the frontend
+	cs.FailAll = true
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("https://testapp", cert)))
+
+	assert.NotContains(t, p.frontendErrors, "testapp")
+}
+
+func TestDeleteFrontendShouldKeepSharedChallengeRoute(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newAutoTLSTestProxy(web, &dummyCertificateManager{})
+
+	fr := p.frontendRepository.(*optionsFrontendRepository)
+	fr.frontendNames = append(fr.frontendNames, "testapp/api")
+	fr.options["testapp/api"] = &interfaces.FrontendOptions{AutoTLS: true}
+
+	p.configureFrontend("testapp")
+	p.configureFrontend("testapp/api")
+
+	challenge, _ := url.Parse("http://testapp/.well-known/acme-challenge")
+
+	p.deleteFrontend("testapp")
+
+	assert.Equal(t, "challenge", web.Handle(challenge, &http.Request{}))
+
+	// disabling automatic TLS for the last frontend deletes the route
+	fr.options["testapp/api"] = nil
+
+	p.configureFrontend("testapp/api")
+
+	assert.NotContains(t, web.routes, challenge.String())
+}
+
+func TestConfigureShouldForgetErrorsOfUnlistedNames(t *testing.T) {
+	sr := &dummyServiceRepository{serviceNames: []string{"testapp", "fail"}}
+	fr := &dummyFrontendRepository{frontendNames: []string{"testapp", "fail"}}
+	p := newTestProxy(sr, fr, &dummyWebServer{})
+
+	p.configure()
+
+	assert.Contains(t, p.serviceErrors, "fail")
+	assert.Contains(t, p.frontendErrors, "fail")
+
+	sr.serviceNames = []string{"testapp"}
+	fr.frontendNames = []string{"testapp"}
+
+	p.configure()
+
+	assert.NotContains(t, p.serviceErrors, "fail")
+	assert.NotContains(t, p.frontendErrors, "fail")
+}
@@ -0,0 +1,127 @@
+package startproxy
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Redirect errors
+var (
+	ErrInvalidRedirectStatus = errors.New("invalid redirect status, must be 301, 302, 307 or 308")
+)
+
+// redirectHandler redirects HTTP requests to HTTPS, preserving their host,
+// path and query.
+type redirectHandler struct {
+	// port of the HTTPS URLs, empty for the default port
+	port   string
+	status int
+
+	// challengeHandler serves the ACME challenge paths instead of
+	// redirecting them, if set
+	challengeHandler http.Handler
+}
+
+// newRedirectHandler creates a handler redirecting to the port of secureURL
+// using status, which defaults to 301 if 0.
+func newRedirectHandler(secureURL *url.URL, status int, challengeHandler http.Handler) (*redirectHandler, error) {
+	switch status {
+	case 0:
+		status = http.StatusMovedPermanently
+	case http.StatusMovedPermanently,
+		http.StatusFound,
+		http.StatusTemporaryRedirect,
+		http.StatusPermanentRedirect:
+	default:
+		return nil, ErrInvalidRedirectStatus
+	}
+
+	port := secureURL.Port()
+	if port == "443" {
+		port = ""
+	}
+
+	return &redirectHandler{
+		port:             port,
+		status:           status,
+		challengeHandler: challengeHandler,
+	}, nil
+}
+
+func (h *redirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.challengeHandler != nil && strings.HasPrefix(r.URL.Path, interfaces.ACMEChallengePath) {
+		h.challengeHandler.ServeHTTP(w, r)
+
+		return
+	}
+
+	http.Redirect(w, r, h.target(r).String(), h.status)
+}
+
+// target returns the HTTPS URL for the request. The host is taken from the
+// request, which was routed on it.
+func (h *redirectHandler) target(r *http.Request) *url.URL {
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	hostname := (&url.URL{Host: host}).Hostname()
+
+	switch {
+	case h.port != "":
+		host = net.JoinHostPort(hostname, h.port)
+	case strings.Contains(hostname, ":"):
+		host = "[" + hostname + "]"
+	default:
+		host = hostname
+	}
+
+	return &url.URL{
+		Scheme:   "https",
+		Host:     host,
+		Path:     r.URL.Path,
+		RawPath:  r.URL.RawPath,
+		RawQuery: r.URL.RawQuery,
+	}
+}
+
+// frontendRedirect returns the handler redirecting the HTTP requests of a
+// frontend to secureURL, as specified by its options. An invalid redirect
+// status is returned as error, in which case the handler uses the default.
+func (p *proxy) frontendRedirect(
+	name string,
+	secureURL *url.URL,
+	options *interfaces.FrontendOptions,
+	serviceHandler http.Handler) (http.Handler, error) {
+	var status int
+	var challengeHandler http.Handler
+
+	if options != nil {
+		status = options.RedirectStatus
+
+		if options.SkipChallengeRedirect {
+			challengeHandler = serviceHandler
+		}
+	}
+
+	redirect, err := newRedirectHandler(secureURL, status, challengeHandler)
+	if err != nil {
+		p.logger.
+			WithError(err).
+			WithField("name", name).
+			WithField("status", status).
+			Error("configuring redirect")
+
+		redirect, _ = newRedirectHandler(secureURL, 0, challengeHandler)
+
+		return redirect, err
+	}
+
+	return redirect, nil
+}
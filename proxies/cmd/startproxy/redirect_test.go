@@ -0,0 +1,114 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestRedirectHandlerShouldPreservePathAndQuery(t *testing.T) {
+	tests := []struct {
+		secureURL string
+		request   string
+		location  string
+	}{
+		{"https://testapp", "http://testapp/some/page?x=1", "https://testapp/some/page?x=1"},
+		{"https://testapp", "http://testapp", "https://testapp"},
+		{"https://testapp:443", "http://testapp:80/a%2Fb", "https://testapp/a%2Fb"},
+		{"https://testapp:8443/app", "http://testapp:8080/app/page", "https://testapp:8443/app/page"},
+		{"https://[::1]", "http://[::1]/page", "https://[::1]/page"},
+		{"https://[::1]:8443", "http://[::1]/page", "https://[::1]:8443/page"},
+	}
+
+	for _, test := range tests {
+		u, _ := url.Parse(test.secureURL)
+
+		h, err := newRedirectHandler(u, 0, nil)
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.request, nil))
+
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, test.request)
+		assert.Equal(t, test.location, w.Header().Get("Location"), test.request)
+	}
+}
+
+func TestRedirectHandlerShouldUseStatus(t *testing.T) {
+	u, _ := url.Parse("https://testapp")
+
+	for _, status := range []int{301, 302, 307, 308} {
+		h, err := newRedirectHandler(u, status, nil)
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://testapp/form", nil))
+
+		assert.Equal(t, status, w.Code)
+	}
+
+	for _, status := range []int{200, 303, 404} {
+		_, err := newRedirectHandler(u, status, nil)
+		assert.Equal(t, ErrInvalidRedirectStatus, err)
+	}
+}
+
+func TestRedirectHandlerShouldSkipChallenges(t *testing.T) {
+	u, _ := url.Parse("https://testapp")
+
+	h, _ := newRedirectHandler(u, 0, namedHandler("service"))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://testapp"+interfaces.ACMEChallengePath+"token", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "service", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://testapp/page", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
+
+func TestUpsertFrontendShouldUseRedirectOptions(t *testing.T) {
+	fr := &optionsFrontendRepository{
+		dummyFrontendRepository: dummyFrontendRepository{frontendNames: []string{"secure-testapp"}},
+		options: map[string]*interfaces.FrontendOptions{
+			"secure-testapp": {RedirectStatus: 308},
+		},
+	}
+
+	web := &dummyWebServer{}
+	p := newTestProxy(&dummyServiceRepository{}, fr, web)
+
+	p.configureFrontend("secure-testapp")
+
+	w := httptest.NewRecorder()
+	web.routes["http://secure-testapp"].ServeHTTP(w,
+		httptest.NewRequest(http.MethodPut, "http://secure-testapp/items/1?v=2", nil))
+
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "https://secure-testapp/items/1?v=2", w.Header().Get("Location"))
+
+	// an invalid status is reported, and the default is used
+	fr.options["secure-testapp"].RedirectStatus = 200
+
+	p.configureFrontend("secure-testapp")
+
+	assert.Equal(t, ErrInvalidRedirectStatus, p.frontendErrors["secure-testapp"])
+
+	w = httptest.NewRecorder()
+	web.routes["http://secure-testapp"].ServeHTTP(w,
+		httptest.NewRequest(http.MethodGet, "http://secure-testapp/", nil))
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+}
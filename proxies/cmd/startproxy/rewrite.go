@@ -0,0 +1,155 @@
+package startproxy
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Path rewrite errors
+var (
+	ErrInvalidPathPrefix  = errors.New("invalid path prefix, must start with /")
+	ErrInvalidStripPrefix = errors.New("invalid strip prefix, must not be /")
+)
+
+// rewriteHandler rewrites the request path before passing the request to
+// the next handler. A stripped prefix is appended to the X-Forwarded-Prefix
+// header forwarded by upstream proxies, if any. If the regular expression or
+// added prefix changes the path, the original request URI is passed in the
+// X-Forwarded-Uri header, unless an upstream proxy already set it.
+type rewriteHandler struct {
+	stripPrefix string
+	regex       *regexp.Regexp
+	replacement string
+	addPrefix   string
+	next        http.Handler
+}
+
+// newRewriteHandler wraps next with the path rewrite. It returns next if the
+// rewrite is nil.
+func newRewriteHandler(rewrite *interfaces.PathRewrite, next http.Handler) (http.Handler, error) {
+	if rewrite == nil {
+		return next, nil
+	}
+
+	for _, prefix := range []string{rewrite.StripPrefix, rewrite.AddPrefix} {
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			return nil, ErrInvalidPathPrefix
+		}
+	}
+
+	// stripping the root would strip nothing
+	if rewrite.StripPrefix != "" && strings.Trim(rewrite.StripPrefix, "/") == "" {
+		return nil, ErrInvalidStripPrefix
+	}
+
+	h := &rewriteHandler{
+		stripPrefix: strings.TrimSuffix(rewrite.StripPrefix, "/"),
+		replacement: rewrite.Replacement,
+		addPrefix:   strings.TrimSuffix(rewrite.AddPrefix, "/"),
+		next:        next,
+	}
+
+	if rewrite.Regex != "" {
+		regex, err := regexp.Compile(rewrite.Regex)
+		if err != nil {
+			return nil, err
+		}
+
+		h.regex = regex
+	}
+
+	return h, nil
+}
+
+func (h *rewriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// copy the request, so the rewrite does not affect the caller
+	rewritten := &http.Request{}
+	*rewritten = *r
+
+	u := &url.URL{}
+	*u = *r.URL
+	rewritten.URL = u
+
+	rewritten.Header = make(http.Header, len(r.Header))
+	for name, values := range r.Header {
+		rewritten.Header[name] = values
+	}
+
+	// rawPath keeps the original encoding of the path, e.g. of %2F, as long
+	// as the path is rewritten by prefixes only
+	path, rawPath := u.Path, u.RawPath
+
+	if h.stripPrefix != "" && hasPathPrefix(path, h.stripPrefix) {
+		path = strings.TrimPrefix(path, h.stripPrefix)
+		rawPath = trimRawPrefix(rawPath, h.stripPrefix)
+
+		upstream := strings.TrimSuffix(r.Header.Get("X-Forwarded-Prefix"), "/")
+		rewritten.Header.Set("X-Forwarded-Prefix", upstream+h.stripPrefix)
+	}
+
+	stripped := ensureLeadingSlash(path)
+
+	if h.regex != nil {
+		path = h.regex.ReplaceAllString(path, h.replacement)
+		rawPath = ""
+	}
+
+	path = ensureLeadingSlash(path)
+
+	if rawPath != "" {
+		rawPath = ensureLeadingSlash(rawPath)
+	}
+
+	if h.addPrefix != "" {
+		path = h.addPrefix + path
+
+		if rawPath != "" {
+			rawPath = escapePath(h.addPrefix) + rawPath
+		}
+	}
+
+	if path != stripped && r.Header.Get("X-Forwarded-Uri") == "" {
+		rewritten.Header.Set("X-Forwarded-Uri", r.URL.RequestURI())
+	}
+
+	u.Path = path
+	u.RawPath = rawPath
+
+	h.next.ServeHTTP(w, rewritten)
+}
+
+// hasPathPrefix returns true if path equals prefix, or continues with a
+// slash after it.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// trimRawPrefix removes the escaped prefix from rawPath. It returns an empty
+// raw path, to encode the path again, if rawPath is empty or does not start
+// with the escaped prefix.
+func trimRawPrefix(rawPath, prefix string) string {
+	escaped := escapePath(prefix)
+
+	if rawPath == "" || !hasPathPrefix(rawPath, escaped) {
+		return ""
+	}
+
+	return strings.TrimPrefix(rawPath, escaped)
+}
+
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
+
+func ensureLeadingSlash(path string) string {
+	if strings.HasPrefix(path, "/") {
+		return path
+	}
+
+	return "/" + path
+}
@@ -0,0 +1,149 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// pathHandler writes the path, raw path, forwarded prefix and forwarded URI
+// it receives.
+var pathHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(strings.Join([]string{
+		r.URL.Path,
+		r.URL.EscapedPath(),
+		r.Header.Get("X-Forwarded-Prefix"),
+		r.Header.Get("X-Forwarded-Uri"),
+	}, " ")))
+})
+
+func TestRewriteHandler(t *testing.T) {
+	tests := []struct {
+		rewrite  *interfaces.PathRewrite
+		request  string
+		expected string
+	}{
+		{&interfaces.PathRewrite{StripPrefix: "/api"}, "/api/users?x=1", "/users /users /api "},
+		{&interfaces.PathRewrite{StripPrefix: "/api/"}, "/api", "/ / /api "},
+		{&interfaces.PathRewrite{StripPrefix: "/api"}, "/apix/users", "/apix/users /apix/users  "},
+		{&interfaces.PathRewrite{StripPrefix: "/api"}, "/api/a%2Fb", "/a/b /a%2Fb /api "},
+		{&interfaces.PathRewrite{AddPrefix: "/v1"}, "/users?x=1", "/v1/users /v1/users  /users?x=1"},
+		{&interfaces.PathRewrite{StripPrefix: "/api", AddPrefix: "/v1/"}, "/api", "/v1/ /v1/ /api /api"},
+		{&interfaces.PathRewrite{Regex: "^/x", Replacement: "/x"}, "/x/users", "/x/users /x/users  "},
+		{
+			&interfaces.PathRewrite{StripPrefix: "/api", Regex: "^/old/", Replacement: "/new/", AddPrefix: "/internal"},
+			"/api/old/page",
+			"/internal/new/page /internal/new/page /api /api/old/page",
+		},
+	}
+
+	for _, test := range tests {
+		h, err := newRewriteHandler(test.rewrite, pathHandler)
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		r := httptest.NewRequest(http.MethodGet, "http://testapp"+test.request, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, test.expected, w.Body.String(), test.request)
+	}
+}
+
+func TestRewriteHandlerShouldForwardOriginalURIOnRegexRewrite(t *testing.T) {
+	tests := []struct {
+		rewrite  *interfaces.PathRewrite
+		request  string
+		expected string
+	}{
+		{&interfaces.PathRewrite{Regex: `^/users/(\d+)$`, Replacement: "/people/$1"}, "/users/42", "/people/42 /people/42  /users/42"},
+		{&interfaces.PathRewrite{Regex: `^/v\d+/`, Replacement: "/"}, "/v2/users?x=1", "/users /users  /v2/users?x=1"},
+	}
+
+	for _, test := range tests {
+		h, _ := newRewriteHandler(test.rewrite, pathHandler)
+
+		r := httptest.NewRequest(http.MethodGet, "http://testapp"+test.request, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		assert.Equal(t, test.expected, w.Body.String(), test.request)
+	}
+}
+
+func TestRewriteHandlerShouldKeepForwardedURI(t *testing.T) {
+	h, _ := newRewriteHandler(&interfaces.PathRewrite{AddPrefix: "/v1"}, pathHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "http://testapp/users", nil)
+	r.Header.Set("X-Forwarded-Uri", "/edge/users")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "/v1/users /v1/users  /edge/users", w.Body.String())
+}
+
+func TestRewriteHandlerShouldAppendToForwardedPrefix(t *testing.T) {
+	h, _ := newRewriteHandler(&interfaces.PathRewrite{StripPrefix: "/api"}, pathHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "http://testapp/api/users", nil)
+	r.Header.Set("X-Forwarded-Prefix", "/edge/")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "/users /users /edge/api ", w.Body.String())
+}
+
+func TestRewriteHandlerShouldNotModifyRequest(t *testing.T) {
+	h, _ := newRewriteHandler(&interfaces.PathRewrite{StripPrefix: "/api"}, pathHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "http://testapp/api/users", nil)
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "/api/users", r.URL.Path)
+	assert.Empty(t, r.Header.Get("X-Forwarded-Prefix"))
+	assert.Empty(t, r.Header.Get("X-Forwarded-Uri"))
+}
+
+func TestNewRewriteHandlerShouldReturnErrors(t *testing.T) {
+	_, err := newRewriteHandler(&interfaces.PathRewrite{StripPrefix: "api"}, pathHandler)
+	assert.Equal(t, ErrInvalidPathPrefix, err)
+
+	_, err = newRewriteHandler(&interfaces.PathRewrite{AddPrefix: "v1"}, pathHandler)
+	assert.Equal(t, ErrInvalidPathPrefix, err)
+
+	_, err = newRewriteHandler(&interfaces.PathRewrite{StripPrefix: "/"}, pathHandler)
+	assert.Equal(t, ErrInvalidStripPrefix, err)
+
+	_, err = newRewriteHandler(&interfaces.PathRewrite{Regex: "("}, pathHandler)
+	assert.Error(t, err)
+
+	h, err := newRewriteHandler(nil, pathHandler)
+	assert.Nil(t, err)
+	assert.NotNil(t, h)
+}
+
+func TestUpsertFrontendShouldRewritePaths(t *testing.T) {
+	web := &dummyWebServer{}
+	p, fr := newMiddlewareTestProxy(web, "auth")
+	p.serviceHandlers["testapp"] = pathHandler
+
+	fr.options["testapp"].PathRewrite = &interfaces.PathRewrite{StripPrefix: "/api"}
+
+	p.configureFrontend("testapp")
+
+	w := httptest.NewRecorder()
+	web.routes["http://testapp"].ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://testapp/api/users", nil))
+
+	assert.Equal(t, "auth,/users /users /api ", w.Body.String())
+}
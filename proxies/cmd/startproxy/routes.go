@@ -0,0 +1,211 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// route models a route installed on one of the web servers.
+type route struct {
+	isSecure      bool
+	isRedirect    bool
+	isChallenge   bool
+	isMaintenance bool
+	url           *url.URL
+	handler       http.Handler
+}
+
+// sameTarget returns true if both routes are installed on the same web
+// server for the same URL, in which case upserting one replaces the other.
+func (r *route) sameTarget(other *route) bool {
+	return r.isSecure == other.isSecure && r.url.String() == other.url.String()
+}
+
+func (c *frontendConfig) addRoute(isSecure bool, u *url.URL, handler http.Handler) {
+	c.routes = append(c.routes, &route{
+		isSecure: isSecure,
+		url:      u,
+		handler:  handler,
+	})
+}
+
+func (c *frontendConfig) addRedirect(u *url.URL, handler http.Handler) {
+	c.routes = append(c.routes, &route{
+		isRedirect: true,
+		url:        u,
+		handler:    handler,
+	})
+}
+
+func (c *frontendConfig) addChallenge(u *url.URL, handler http.Handler) {
+	c.routes = append(c.routes, &route{
+		isChallenge: true,
+		url:         u,
+		handler:     handler,
+	})
+}
+
+func (c *frontendConfig) addMaintenance(isSecure bool, u *url.URL, handler http.Handler) {
+	c.routes = append(c.routes, &route{
+		isSecure:      isSecure,
+		isMaintenance: true,
+		url:           u,
+		handler:       handler,
+	})
+}
+
+// addSecureRoutes adds the HTTPS route to the service handler, and the HTTP
+// route using the redirect handler. If the certificate for the host has expired and a
+// maintenance handler is set, both routes serve the maintenance handler
+// instead.
+func (p *proxy) addSecureRoutes(
+	config *frontendConfig,
+	secureURL *url.URL,
+	serviceHandler, redirect http.Handler) {
+	if p.maintenanceHandler != nil && p.certificateExpired(secureURL.Hostname(), time.Now()) {
+		config.maintenance = true
+
+		config.addMaintenance(true, secureURL, p.maintenanceHandler)
+		config.addMaintenance(false, httpURL(secureURL), p.maintenanceHandler)
+
+		return
+	}
+
+	config.addRoute(true, secureURL, serviceHandler)
+
+	config.addRedirect(httpURL(secureURL), redirect)
+}
+
+// findRoute returns the route of this config with the same target as the
+// provided route, or nil if it has none. It is safe to call on a nil config.
+func (c *frontendConfig) findRoute(other *route) *route {
+	if c == nil {
+		return nil
+	}
+
+	for _, r := range c.routes {
+		if r.sameTarget(other) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (p *proxy) getWebServer(isSecure bool) interfaces.WebServer {
+	if isSecure {
+		return p.secureWebServer
+	}
+
+	return p.webServer
+}
+
+// installRoutes upserts the routes of config and deletes the routes of the
+// previous config that are no longer used. If any route fails to upsert, the
+// changes are rolled back so that the previous routes stay in place.
+func (p *proxy) installRoutes(config, previous *frontendConfig) error {
+	var installed []*route
+
+	for _, r := range config.routes {
+		err := p.getWebServer(r.isSecure).UpsertRoute(r.url, r.handler)
+		if err != nil {
+			p.logger.
+				WithError(err).
+				WithField("url", r.url).
+				Error("upserting route")
+
+			p.rollbackRoutes(installed, previous)
+
+			return err
+		}
+
+		installed = append(installed, r)
+	}
+
+	if previous == nil {
+		return nil
+	}
+
+	for _, r := range previous.routes {
+		if config.findRoute(r) != nil {
+			// replaced by the new config
+			continue
+		}
+
+		p.deleteRoute(r, previous)
+	}
+
+	return nil
+}
+
+// rollbackRoutes restores the previous routes replaced by the installed ones,
+// and deletes the installed routes that did not exist previously.
+func (p *proxy) rollbackRoutes(installed []*route, previous *frontendConfig) {
+	for _, r := range installed {
+		prev := previous.findRoute(r)
+		if prev == nil {
+			p.deleteRoute(r, previous)
+
+			continue
+		}
+
+		err := p.getWebServer(prev.isSecure).UpsertRoute(prev.url, prev.handler)
+		if err != nil {
+			p.logger.
+				WithError(err).
+				WithField("url", prev.url).
+				Error("restoring route")
+		}
+	}
+}
+
+// deleteRoute deletes the route from its web server. Challenge routes are
+// shared by all frontends with automatic TLS for the same host, so these are
+// kept as long as a frontend config other than owner uses them.
+func (p *proxy) deleteRoute(r *route, owner *frontendConfig) {
+	if r.isChallenge {
+		for _, config := range p.frontendConfigs {
+			if config == owner {
+				continue
+			}
+
+			if other := config.findRoute(r); other != nil && other.isChallenge {
+				return
+			}
+		}
+	}
+
+	p.getWebServer(r.isSecure).DeleteRoute(r.url)
+}
+
+// httpURL returns a copy of u with its scheme set to HTTP.
+func httpURL(u *url.URL) *url.URL {
+	httpURL := &url.URL{}
+	*httpURL = *u
+	httpURL.Scheme = "http"
+
+	return httpURL
+}
+
+// httpsURL returns a copy of u with its scheme set to HTTPS.
+func httpsURL(u *url.URL) *url.URL {
+	httpsURL := &url.URL{}
+	*httpsURL = *u
+	httpsURL.Scheme = "https"
+
+	return httpsURL
+}
+
+// challengeURL returns the HTTP URL on which the ACME HTTP-01 challenges for
+// the host of u are served.
+func challengeURL(u *url.URL) *url.URL {
+	return &url.URL{
+		Scheme: "http",
+		Host:   u.Host,
+		Path:   strings.TrimSuffix(interfaces.ACMEChallengePath, "/"),
+	}
+}
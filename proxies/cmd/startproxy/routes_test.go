@@ -0,0 +1,136 @@
+package startproxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/frontends"
+)
+
+func frontendWithURL(rawurl string, cert *frontends.Certificate) *frontends.Frontend {
+	f, err := frontends.NewFrontend("testapp", rawurl, cert, "testapp")
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return f
+}
+
+func upsertFrontendEvent(f *frontends.Frontend) interfaces.FrontendEvent {
+	return interfaces.FrontendEvent{
+		Name:     f.Name,
+		Kind:     interfaces.EventUpdated,
+		Frontend: f,
+	}
+}
+
+func TestUpsertFrontendShouldDeleteRoutesOfPreviousURL(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(&dummyServiceRepository{}, &dummyFrontendRepository{}, web)
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("http://old/path", nil)))
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("http://new/path", nil)))
+
+	assert.NotContains(t, web.routes, "http://old/path")
+	assert.Contains(t, web.routes, "http://new/path")
+}
+
+func TestUpsertFrontendShouldDeleteRoutesWhenCertificateIsRemoved(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.serviceHandlers["testapp"] = namedHandler("service")
+
+	p.handleFrontendEvent(upsertFrontendEvent(
+		frontendWithURL("https://testapp", &frontends.Certificate{})))
+
+	assert.Contains(t, web.routes, "https://testapp")
+	assert.Contains(t, web.routes, "http://testapp")
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("http://testapp", nil)))
+
+	assert.NotContains(t, web.routes, "https://testapp")
+
+	// the redirect must have been replaced by the service handler
+	u, _ := url.Parse("http://testapp")
+	assert.Equal(t, "service", web.Handle(u, &http.Request{}))
+}
+
+func TestUpsertFrontendShouldDeleteRoutesWhenCertificateIsAdded(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("http://testapp/old", nil)))
+	p.handleFrontendEvent(upsertFrontendEvent(
+		frontendWithURL("https://testapp/new", &frontends.Certificate{})))
+
+	assert.NotContains(t, web.routes, "http://testapp/old")
+	assert.Contains(t, web.routes, "https://testapp/new")
+	assert.Contains(t, web.routes, "http://testapp/new")
+}
+
+func TestUpsertFrontendShouldKeepPreviousRoutesOnFailure(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.handleFrontendEvent(upsertFrontendEvent(frontendWithURL("http://testapp", nil)))
+
+	previous := p.frontendConfigs["testapp"]
+
+	// installing the HTTPS route succeeds, replacing the plain route fails
+	web.FailRoutes = map[string]bool{"http://testapp": true}
+
+	p.handleFrontendEvent(upsertFrontendEvent(
+		frontendWithURL("https://testapp", &frontends.Certificate{})))
+
+	assert.Equal(t, previous, p.frontendConfigs["testapp"])
+	assert.NotContains(t, web.routes, "https://testapp")
+	assert.Contains(t, web.routes, "http://testapp")
+}
+
+func TestUpsertFrontendShouldRestoreReplacedRoutesOnFailure(t *testing.T) {
+	web := &dummyWebServer{}
+	p := newTestProxy(
+		&dummyServiceRepository{},
+		&dummyFrontendRepository{},
+		web)
+
+	p.serviceHandlers["testapp"] = namedHandler("testapp")
+	p.serviceHandlers["other"] = namedHandler("other")
+
+	p.handleFrontendEvent(upsertFrontendEvent(
+		frontendWithURL("https://testapp", &frontends.Certificate{})))
+
+	// switch service: the HTTPS route is replaced, the redirect fails
+	f := frontendWithURL("https://testapp", &frontends.Certificate{})
+	f.ServiceName = "other"
+
+	web.FailRoutes = map[string]bool{"http://testapp": true}
+
+	p.handleFrontendEvent(upsertFrontendEvent(f))
+
+	assert.Equal(t, "testapp", p.frontendConfigs["testapp"].serviceName)
+
+	u, _ := url.Parse("https://testapp")
+	assert.Equal(t, "testapp", web.Handle(u, &http.Request{}))
+}
+
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	})
+}
@@ -0,0 +1,93 @@
+package startproxy
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-app/proxies/middleware"
+)
+
+// Security header errors
+var (
+	ErrInvalidHSTSMaxAge  = errors.New("invalid HSTS max age, must be at least 1 second")
+	ErrInvalidHSTSPreload = errors.New("invalid HSTS preload, requires includeSubDomains and a max age of at least 1 year")
+)
+
+// minHSTSPreloadMaxAge is the minimum max age accepted by the preload lists.
+const minHSTSPreloadMaxAge = 365 * day
+
+// newSecurityHeadersHandler wraps next with the headers of the policy, which
+// replace any values set by next. It returns next if the policy is nil.
+func newSecurityHeadersHandler(policy *interfaces.SecurityHeaders, next http.Handler) (http.Handler, error) {
+	if policy == nil {
+		return next, nil
+	}
+
+	headers, err := securityHeaders(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return middleware.ResponseHeaders(headers).Wrap(next), nil
+}
+
+// securityHeaders returns the headers specified by the policy.
+func securityHeaders(policy *interfaces.SecurityHeaders) (map[string]string, error) {
+	headers := make(map[string]string)
+
+	for name, value := range policy.Headers {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	if policy.HSTS != nil {
+		value, err := hstsHeader(policy.HSTS)
+		if err != nil {
+			return nil, err
+		}
+
+		headers["Strict-Transport-Security"] = value
+	}
+
+	if policy.ContentTypeNosniff {
+		headers["X-Content-Type-Options"] = "nosniff"
+	}
+
+	if policy.FrameOptions != "" {
+		headers["X-Frame-Options"] = policy.FrameOptions
+	}
+
+	if policy.ContentSecurityPolicy != "" {
+		headers["Content-Security-Policy"] = policy.ContentSecurityPolicy
+	}
+
+	if policy.ReferrerPolicy != "" {
+		headers["Referrer-Policy"] = policy.ReferrerPolicy
+	}
+
+	return headers, nil
+}
+
+func hstsHeader(hsts *interfaces.HSTS) (string, error) {
+	if hsts.MaxAge < time.Second {
+		return "", ErrInvalidHSTSMaxAge
+	}
+
+	if hsts.Preload && (!hsts.IncludeSubDomains || hsts.MaxAge < minHSTSPreloadMaxAge) {
+		return "", ErrInvalidHSTSPreload
+	}
+
+	value := "max-age=" + strconv.FormatInt(int64(hsts.MaxAge/time.Second), 10)
+
+	if hsts.IncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+
+	if hsts.Preload {
+		value += "; preload"
+	}
+
+	return value, nil
+}
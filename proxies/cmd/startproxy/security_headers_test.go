@@ -0,0 +1,164 @@
+package startproxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestSecurityHeadersHandler(t *testing.T) {
+	service := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "ALLOWALL")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("service"))
+	})
+
+	h, err := newSecurityHeadersHandler(&interfaces.SecurityHeaders{
+		HSTS: &interfaces.HSTS{
+			MaxAge:            365 * day,
+			IncludeSubDomains: true,
+			Preload:           true,
+		},
+		ContentTypeNosniff:    true,
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "default-src 'self'",
+		ReferrerPolicy:        "no-referrer",
+		Headers:               map[string]string{"permissions-policy": "geolocation=()"},
+	}, service)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://testapp/", nil))
+
+	assert.Equal(t, "service", w.Body.String())
+	assert.Equal(t, "max-age=31536000; includeSubDomains; preload", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, []string{"DENY"}, w.Header()["X-Frame-Options"])
+	assert.Equal(t, "default-src 'self'", w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "geolocation=()", w.Header().Get("Permissions-Policy"))
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+}
+
+func TestSecurityHeadersHandlerShouldApplyToErrorResponses(t *testing.T) {
+	h, _ := newSecurityHeadersHandler(&interfaces.SecurityHeaders{
+		HSTS: &interfaces.HSTS{MaxAge: time.Hour},
+	}, http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://testapp/", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "max-age=3600", w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestNewSecurityHeadersHandlerShouldRejectInvalidHSTS(t *testing.T) {
+	tests := []struct {
+		hsts     *interfaces.HSTS
+		expected error
+	}{
+		{&interfaces.HSTS{}, ErrInvalidHSTSMaxAge},
+		{&interfaces.HSTS{MaxAge: 365 * day, Preload: true}, ErrInvalidHSTSPreload},
+		{&interfaces.HSTS{MaxAge: 30 * day, IncludeSubDomains: true, Preload: true}, ErrInvalidHSTSPreload},
+	}
+
+	for _, test := range tests {
+		_, err := newSecurityHeadersHandler(&interfaces.SecurityHeaders{HSTS: test.hsts}, namedHandler("service"))
+
+		assert.Equal(t, test.expected, err)
+	}
+}
+
+func TestUpsertFrontendShouldAddSecurityHeaders(t *testing.T) {
+	fr := &optionsFrontendRepository{
+		dummyFrontendRepository: dummyFrontendRepository{frontendNames: []string{"secure-testapp"}},
+		options: map[string]*interfaces.FrontendOptions{
+			"secure-testapp": {
+				SecurityHeaders: &interfaces.SecurityHeaders{
+					HSTS: &interfaces.HSTS{MaxAge: time.Hour},
+				},
+			},
+		},
+	}
+
+	web := &dummyWebServer{}
+	p := newTestProxy(&dummyServiceRepository{}, fr, web)
+	p.serviceHandlers["secure-testapp"] = namedHandler("service")
+
+	p.configureFrontend("secure-testapp")
+
+	w := httptest.NewRecorder()
+	web.routes["https://secure-testapp"].ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://secure-testapp/", nil))
+
+	assert.Equal(t, "service", w.Body.String())
+	assert.Equal(t, "max-age=3600", w.Header().Get("Strict-Transport-Security"))
+
+	// the HTTP redirect does not carry HSTS
+	w = httptest.NewRecorder()
+	web.routes["http://secure-testapp"].ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://secure-testapp/", nil))
+
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+
+	// an invalid policy keeps the previous routes in place
+	fr.options["secure-testapp"].SecurityHeaders.HSTS.MaxAge = 0
+
+	p.configureFrontend("secure-testapp")
+
+	assert.Equal(t, ErrInvalidHSTSMaxAge, p.frontendErrors["secure-testapp"])
+
+	u, _ := url.Parse("https://secure-testapp")
+	assert.Equal(t, "service", web.Handle(u, &http.Request{}))
+}
+
+func TestSecurityHeadersHandlerShouldApplyToUpgradeResponses(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		rw.Flush()
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+
+	h, _ := newSecurityHeadersHandler(&interfaces.SecurityHeaders{
+		ContentTypeNosniff: true,
+	}, httputil.NewSingleHostReverseProxy(backendURL))
+
+	proxy := httptest.NewServer(h)
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "nosniff", res.Header.Get("X-Content-Type-Options"))
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// newRequestHeaders creates a middleware setting the request headers named
+// by the options to their values. An empty value removes the header.
+func newRequestHeaders(options map[string]string) (interfaces.Middleware, error) {
+	headers := canonicalHeaders(options)
+
+	return interfaces.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// copy the request, so the headers of the caller are not affected
+			modified := &http.Request{}
+			*modified = *r
+
+			modified.Header = make(http.Header, len(r.Header))
+			for name, values := range r.Header {
+				modified.Header[name] = values
+			}
+
+			applyHeaders(modified.Header, headers)
+
+			next.ServeHTTP(w, modified)
+		})
+	}), nil
+}
+
+// newResponseHeaders creates a middleware setting the response headers named
+// by the options to their values, replacing the values set by the service.
+// An empty value removes the header.
+func newResponseHeaders(options map[string]string) (interfaces.Middleware, error) {
+	return ResponseHeaders(options), nil
+}
+
+// ResponseHeaders returns a middleware setting the response headers to their
+// values right before the response header is written, replacing the values
+// set by the next handler. An empty value removes the header.
+func ResponseHeaders(headers map[string]string) interfaces.Middleware {
+	headers = canonicalHeaders(headers)
+
+	return interfaces.MiddlewareFunc(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&headersWriter{ResponseWriter: w, headers: headers}, r)
+		})
+	})
+}
+
+// headersWriter applies the headers right before the response header is
+// written.
+type headersWriter struct {
+	http.ResponseWriter
+	headers     map[string]string
+	wroteHeader bool
+}
+
+func (w *headersWriter) WriteHeader(status int) {
+	w.applyHeaders()
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headersWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, so that streaming responses are flushed.
+func (w *headersWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, so that upgraded connections such as
+// websockets are supported. The headers are applied first, as the upgrade
+// response is written using the headers of the response writer.
+func (w *headersWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+
+	w.applyHeaders()
+
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying response writer.
+func (w *headersWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headersWriter) applyHeaders() {
+	if w.wroteHeader {
+		return
+	}
+
+	w.wroteHeader = true
+
+	applyHeaders(w.Header(), w.headers)
+}
+
+func canonicalHeaders(options map[string]string) map[string]string {
+	headers := make(map[string]string, len(options))
+	for name, value := range options {
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	return headers
+}
+
+func applyHeaders(h http.Header, headers map[string]string) {
+	for name, value := range headers {
+		if value == "" {
+			h.Del(name)
+
+			continue
+		}
+
+		h.Set(name, value)
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	m, err := NewRegistry().CreateMiddleware(&interfaces.MiddlewareConfig{
+		Name: "requestHeaders",
+		Options: map[string]string{
+			"x-forwarded-proto": "https",
+			"Authorization":     "",
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var received http.Header
+
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "http://testapp/", nil)
+	r.Header.Set("Authorization", "Basic secret")
+
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "https", received.Get("X-Forwarded-Proto"))
+	assert.NotContains(t, received, "Authorization")
+
+	// the request of the caller is not modified
+	assert.Equal(t, "Basic secret", r.Header.Get("Authorization"))
+	assert.NotContains(t, r.Header, "X-Forwarded-Proto")
+}
+
+func TestResponseHeaders(t *testing.T) {
+	m, err := NewRegistry().CreateMiddleware(&interfaces.MiddlewareConfig{
+		Name: "responseHeaders",
+		Options: map[string]string{
+			"Cache-Control": "no-store",
+			"Server":        "",
+		},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Cache-Control", "max-age=60")
+		w.Header().Set("Server", "backend/1.0")
+		w.Write([]byte("service"))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://testapp/", nil))
+
+	assert.Equal(t, "service", w.Body.String())
+	assert.Equal(t, []string{"no-store"}, w.Header()["Cache-Control"])
+	assert.NotContains(t, w.Header(), "Server")
+}
+
+func TestResponseHeadersShouldNotSupportHijackingUnlessUnderlyingWriterDoes(t *testing.T) {
+	m, _ := NewRegistry().CreateMiddleware(&interfaces.MiddlewareConfig{Name: "responseHeaders"})
+
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, err := w.(http.Hijacker).Hijack()
+		assert.Equal(t, http.ErrNotSupported, err)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://testapp/", nil))
+}
+
+func TestResponseHeadersShouldApplyToUpgradeResponses(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+		rw.Flush()
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+
+	m, _ := NewRegistry().CreateMiddleware(&interfaces.MiddlewareConfig{
+		Name:    "responseHeaders",
+		Options: map[string]string{"X-Proxy": "test"},
+	})
+
+	proxy := httptest.NewServer(m.Wrap(httputil.NewSingleHostReverseProxy(backendURL)))
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	defer conn.Close()
+
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	assert.Equal(t, "test", res.Header.Get("X-Proxy"))
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Rate limit errors
+var (
+	ErrInvalidRate  = errors.New("invalid rate, must be a number greater than 0")
+	ErrInvalidBurst = errors.New("invalid burst, must be an integer greater than 0")
+	ErrInvalidKey   = errors.New("invalid key, must be ip, global or header:<name>")
+)
+
+// rateLimitSweepInterval is the minimum time between removing the buckets
+// that are full, to bound the memory used for clients that went away.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimitConfig holds the parsed options of a rate limit.
+type rateLimitConfig struct {
+	// rate is the number of tokens added per second
+	rate  float64
+	burst float64
+
+	// keys: global, the header if set, or the client IP otherwise
+	global bool
+	header string
+}
+
+// sameKeys returns true if both configs key the buckets the same way.
+func (c *rateLimitConfig) sameKeys(other *rateLimitConfig) bool {
+	return c.global == other.global && c.header == other.header
+}
+
+// parseRateLimitConfig parses the options of a rate limit:
+//
+//	rate   requests per second, e.g. 10 or 0.5
+//	burst  number of requests allowed at once, defaults to the rate
+//	       rounded up
+//	key    ip (default), global, or header:<name>, e.g. header:X-Api-Key
+func parseRateLimitConfig(options map[string]string) (*rateLimitConfig, error) {
+	rate, err := strconv.ParseFloat(options["rate"], 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return nil, ErrInvalidRate
+	}
+
+	config := &rateLimitConfig{
+		rate:  rate,
+		burst: math.Ceil(rate),
+	}
+
+	if s, found := options["burst"]; found {
+		burst, err := strconv.Atoi(s)
+		if err != nil || burst < 1 {
+			return nil, ErrInvalidBurst
+		}
+
+		config.burst = float64(burst)
+	}
+
+	switch key := options["key"]; {
+	case key == "" || key == "ip":
+	case key == "global":
+		config.global = true
+	case strings.HasPrefix(key, "header:") && len(key) > len("header:"):
+		config.header = http.CanonicalHeaderKey(strings.TrimPrefix(key, "header:"))
+	default:
+		return nil, ErrInvalidKey
+	}
+
+	return config, nil
+}
+
+// bucket holds the tokens of a key, as of the last request.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimit is a token bucket rate limit. Each key has a bucket holding up
+// to burst tokens, which is refilled at rate tokens per second. A request
+// takes one token, or is rejected with 429 Too Many Requests if the bucket
+// is empty.
+type rateLimit struct {
+	mu        sync.Mutex
+	config    *rateLimitConfig
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newRateLimit creates a rate limit middleware, see parseRateLimitConfig for
+// its options.
+func newRateLimit(options map[string]string) (interfaces.Middleware, error) {
+	config, err := parseRateLimitConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rateLimit{
+		config:  config,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}, nil
+}
+
+// Reconfigure implements interfaces.ReconfigurableMiddleware. The buckets
+// are kept, with their tokens limited to the new burst, unless the key
+// changes.
+func (l *rateLimit) Reconfigure(options map[string]string) error {
+	config, err := parseRateLimitConfig(options)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !config.sameKeys(l.config) {
+		l.buckets = make(map[string]*bucket)
+	}
+
+	l.config = config
+
+	return nil
+}
+
+// Wrap implements interfaces.Middleware.
+func (l *rateLimit) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter, allowed := l.allow(r); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket of the request. If the bucket is
+// empty, it returns the number of seconds until a token is available.
+func (l *rateLimit) allow(r *http.Request) (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	key := l.key(r)
+
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: l.config.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return 0, true
+	}
+
+	retryAfter := int(math.Ceil((1 - b.tokens) / l.config.rate))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	return retryAfter, false
+}
+
+// refill returns the tokens of the bucket at time now.
+func (l *rateLimit) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.last).Seconds()*l.config.rate
+
+	return math.Min(tokens, l.config.burst)
+}
+
+// sweep removes the buckets that are full, as they are the same as new
+// buckets.
+func (l *rateLimit) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.config.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// key returns the bucket key of the request. Requests without the header
+// are keyed on their client IP.
+func (l *rateLimit) key(r *http.Request) string {
+	if l.config.global {
+		return ""
+	}
+
+	if l.config.header != "" {
+		if value := r.Header.Get(l.config.header); value != "" {
+			return "header:" + value
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
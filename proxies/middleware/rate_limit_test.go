@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// testClock is a manually advanced clock.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestRateLimit(t *testing.T, options map[string]string) (*rateLimit, *testClock, http.Handler) {
+	m, err := newRateLimit(options)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	clock := &testClock{now: time.Unix(1500000000, 0)}
+
+	l := m.(*rateLimit)
+	l.now = clock.Now
+
+	return l, clock, l.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("service"))
+	}))
+}
+
+func request(h http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "http://testapp/", nil)
+	r.RemoteAddr = remoteAddr
+
+	for name, values := range header {
+		r.Header[name] = values
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestRateLimitShouldRejectRequestsOverBurst(t *testing.T) {
+	_, clock, h := newTestRateLimit(t, map[string]string{"rate": "0.5", "burst": "2"})
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+
+	w := request(h, "10.0.0.1:1234", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// other clients have their own bucket
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.2:1234", nil).Code)
+
+	// a token is added every 2 seconds
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+
+	w = request(h, "10.0.0.1:5678", nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+}
+
+func TestRateLimitShouldKeyOnHeader(t *testing.T) {
+	_, _, h := newTestRateLimit(t, map[string]string{"rate": "1", "key": "header:x-api-key"})
+
+	key1 := http.Header{"X-Api-Key": {"key1"}}
+	key2 := http.Header{"X-Api-Key": {"key2"}}
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", key1).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.2:1234", key1).Code)
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", key2).Code)
+
+	// requests without the header are keyed on their client IP
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.1:1234", nil).Code)
+}
+
+func TestRateLimitShouldKeyGlobally(t *testing.T) {
+	_, _, h := newTestRateLimit(t, map[string]string{"rate": "1", "key": "global"})
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.2:1234", nil).Code)
+}
+
+func TestRateLimitReconfigureShouldKeepBuckets(t *testing.T) {
+	l, _, h := newTestRateLimit(t, map[string]string{"rate": "1", "burst": "3"})
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+
+	// the bucket keeps its remaining token
+	assert.Nil(t, l.Reconfigure(map[string]string{"rate": "1", "burst": "10"}))
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request(h, "10.0.0.1:1234", nil).Code)
+
+	// invalid options leave the rate limit unchanged
+	assert.Equal(t, ErrInvalidRate, l.Reconfigure(map[string]string{"rate": "0"}))
+	assert.Equal(t, 10.0, l.config.burst)
+
+	// changing the key resets the buckets
+	assert.Nil(t, l.Reconfigure(map[string]string{"rate": "1", "key": "global"}))
+
+	assert.Equal(t, http.StatusOK, request(h, "10.0.0.1:1234", nil).Code)
+}
+
+func TestRateLimitShouldSweepFullBuckets(t *testing.T) {
+	l, clock, h := newTestRateLimit(t, map[string]string{"rate": "1"})
+
+	request(h, "10.0.0.1:1234", nil)
+	request(h, "10.0.0.2:1234", nil)
+
+	assert.Len(t, l.buckets, 2)
+
+	clock.now = clock.now.Add(rateLimitSweepInterval)
+
+	request(h, "10.0.0.3:1234", nil)
+
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestParseRateLimitConfigShouldReturnErrors(t *testing.T) {
+	tests := []struct {
+		options  map[string]string
+		expected error
+	}{
+		{map[string]string{}, ErrInvalidRate},
+		{map[string]string{"rate": "-1"}, ErrInvalidRate},
+		{map[string]string{"rate": "fast"}, ErrInvalidRate},
+		{map[string]string{"rate": "1", "burst": "0"}, ErrInvalidBurst},
+		{map[string]string{"rate": "1", "burst": "1.5"}, ErrInvalidBurst},
+		{map[string]string{"rate": "1", "key": "cookie"}, ErrInvalidKey},
+		{map[string]string{"rate": "1", "key": "header:"}, ErrInvalidKey},
+	}
+
+	for _, test := range tests {
+		_, err := parseRateLimitConfig(test.options)
+		assert.Equal(t, test.expected, err, "%v", test.options)
+	}
+
+	config, err := parseRateLimitConfig(map[string]string{"rate": "2.5"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, 3.0, config.burst)
+	}
+}
+
+func TestRegistryShouldCreateRateLimit(t *testing.T) {
+	m, err := NewRegistry().CreateMiddleware(&interfaces.MiddlewareConfig{
+		Name:    "rateLimit",
+		Options: map[string]string{"rate": "10"},
+	})
+
+	assert.Nil(t, err)
+	assert.Implements(t, (*interfaces.ReconfigurableMiddleware)(nil), m)
+}
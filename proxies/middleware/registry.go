@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingName         = errors.New("missing middleware name")
+	ErrMissingFactory      = errors.New("missing middleware factory")
+	ErrDuplicateMiddleware = errors.New("middleware already registered")
+	ErrMissingConfig       = errors.New("missing middleware config")
+)
+
+// Factory creates a middleware configured using options. It returns an
+// error if the options are invalid.
+type Factory func(options map[string]string) (interfaces.Middleware, error)
+
+// Registry implements interfaces.MiddlewareRegistry. It is safe for
+// concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a registry containing the built-in middlewares:
+//
+//	rateLimit        limits the requests per client, see parseRateLimitConfig
+//	requestHeaders   sets the request headers named by the options
+//	responseHeaders  sets the response headers named by the options
+func NewRegistry() *Registry {
+	r := &Registry{
+		factories: make(map[string]Factory),
+	}
+
+	r.factories["rateLimit"] = newRateLimit
+	r.factories["requestHeaders"] = newRequestHeaders
+	r.factories["responseHeaders"] = newResponseHeaders
+
+	return r
+}
+
+// Register adds a middleware factory under name.
+func (r *Registry) Register(name string, factory Factory) error {
+	if name == "" {
+		return ErrMissingName
+	}
+
+	if factory == nil {
+		return ErrMissingFactory
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.factories[name]; found {
+		return ErrDuplicateMiddleware
+	}
+
+	r.factories[name] = factory
+
+	return nil
+}
+
+// Names returns the names of the registered middlewares, in order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// CreateMiddleware implements interfaces.MiddlewareRegistry.
+func (r *Registry) CreateMiddleware(config *interfaces.MiddlewareConfig) (interfaces.Middleware, error) {
+	if config == nil {
+		return nil, ErrMissingConfig
+	}
+
+	r.mu.RLock()
+	factory, found := r.factories[config.Name]
+	r.mu.RUnlock()
+
+	if !found {
+		return nil, interfaces.ErrUnknownMiddleware
+	}
+
+	m, err := factory(config.Options)
+	if err != nil {
+		return nil, fmt.Errorf("middleware %s: %v", config.Name, err)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+func TestNewRegistryShouldContainBuiltins(t *testing.T) {
+	r := NewRegistry()
+
+	assert.Equal(t, []string{"rateLimit", "requestHeaders", "responseHeaders"}, r.Names())
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+
+	factory := func(options map[string]string) (interfaces.Middleware, error) {
+		return interfaces.MiddlewareFunc(func(next http.Handler) http.Handler {
+			return next
+		}), nil
+	}
+
+	assert.Nil(t, r.Register("noop", factory))
+	assert.Equal(t, ErrDuplicateMiddleware, r.Register("noop", factory))
+	assert.Equal(t, ErrMissingName, r.Register("", factory))
+	assert.Equal(t, ErrMissingFactory, r.Register("other", nil))
+
+	m, err := r.CreateMiddleware(&interfaces.MiddlewareConfig{Name: "noop"})
+	assert.Nil(t, err)
+	assert.NotNil(t, m)
+}
+
+func TestCreateMiddlewareShouldReturnErrors(t *testing.T) {
+	r := NewRegistry()
+
+	r.Register("failing", func(options map[string]string) (interfaces.Middleware, error) {
+		return nil, errors.New("invalid options")
+	})
+
+	_, err := r.CreateMiddleware(nil)
+	assert.Equal(t, ErrMissingConfig, err)
+
+	_, err = r.CreateMiddleware(&interfaces.MiddlewareConfig{Name: "unknown"})
+	assert.Equal(t, interfaces.ErrUnknownMiddleware, err)
+
+	_, err = r.CreateMiddleware(&interfaces.MiddlewareConfig{Name: "failing"})
+	assert.EqualError(t, err, "middleware failing: invalid options")
+}
@@ -0,0 +1,8 @@
+package getcertificateexpiry
+
+// Model specifies the input for the Query.
+type Model struct {
+	// Host is optional. If set, only the certificate serving this host is
+	// returned.
+	Host string
+}
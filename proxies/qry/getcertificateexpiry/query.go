@@ -0,0 +1,52 @@
+package getcertificateexpiry
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingCertificateExpiryReporter = errors.New("missing certificate expiry reporter")
+)
+
+// Query implements the Get Certificate Expiry Query. It requires a
+// CertificateExpiryReporter, such as the proxy returned by the Start Proxy
+// Command.
+type Query struct {
+	reporter interfaces.CertificateExpiryReporter
+}
+
+// NewQuery creates a new Get Certificate Expiry Query
+func NewQuery(reporter interfaces.CertificateExpiryReporter) (*Query, error) {
+	if reporter == nil {
+		return nil, ErrMissingCertificateExpiryReporter
+	}
+
+	return &Query{
+		reporter: reporter,
+	}, nil
+}
+
+// Execute performs the Get Certificate Expiry Query using the provided model.
+func (q *Query) Execute(model *Model) (*Result, error) {
+	expiries := q.reporter.CertificateExpiries()
+
+	if model.Host == "" {
+		return &Result{
+			Certificates: expiries,
+		}, nil
+	}
+
+	result := &Result{}
+
+	for _, expiry := range expiries {
+		if strings.EqualFold(expiry.Host, model.Host) {
+			result.Certificates = append(result.Certificates, expiry)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package getcertificateexpiry
+
+import (
+	"testing"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	q, err := NewQuery(&dummyReporter{})
+
+	assert.NotNil(t, q)
+	assert.Nil(t, err)
+}
+
+func TestNewShouldReturnErrorOnMissingReporter(t *testing.T) {
+	q, err := NewQuery(nil)
+
+	assert.Nil(t, q)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, ErrMissingCertificateExpiryReporter, err)
+}
+
+func TestExecute(t *testing.T) {
+	q, _ := NewQuery(&dummyReporter{
+		expiries: []*interfaces.CertificateExpiry{
+			{Host: "api.example.com", DomainName: "*.example.com", DaysToExpiry: 12},
+			{Host: "www.example.com", DomainName: "*.example.com", DaysToExpiry: 12},
+		},
+	})
+
+	r, err := q.Execute(&Model{})
+
+	assert.Nil(t, err)
+	assert.Len(t, r.Certificates, 2)
+
+	r, err = q.Execute(&Model{Host: "WWW.example.com"})
+
+	assert.Nil(t, err)
+	if assert.Len(t, r.Certificates, 1) {
+		assert.Equal(t, "www.example.com", r.Certificates[0].Host)
+		assert.Equal(t, 12, r.Certificates[0].DaysToExpiry)
+	}
+}
+
+type dummyReporter struct {
+	expiries []*interfaces.CertificateExpiry
+}
+
+func (r *dummyReporter) CertificateExpiries() []*interfaces.CertificateExpiry {
+	return r.expiries
+}
@@ -0,0 +1,8 @@
+package getcertificateexpiry
+
+import "github.com/off-sync/platform-proxy-app/interfaces"
+
+// Result specifies the output of the Query.
+type Result struct {
+	Certificates []*interfaces.CertificateExpiry
+}
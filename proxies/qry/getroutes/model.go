@@ -0,0 +1,5 @@
+package getroutes
+
+// Model specifies the input for the Query.
+type Model struct {
+}
@@ -0,0 +1,36 @@
+package getroutes
+
+import (
+	"errors"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingRouteTable = errors.New("missing route table")
+)
+
+// Query implements the Get Routes Query. It requires a RouteTable, such as
+// the proxy returned by the Start Proxy Command.
+type Query struct {
+	table interfaces.RouteTable
+}
+
+// NewQuery creates a new Get Routes Query
+func NewQuery(table interfaces.RouteTable) (*Query, error) {
+	if table == nil {
+		return nil, ErrMissingRouteTable
+	}
+
+	return &Query{
+		table: table,
+	}, nil
+}
+
+// Execute performs the Get Routes Query using the provided model.
+func (q *Query) Execute(model *Model) (*Result, error) {
+	return &Result{
+		Routes: q.table.Routes(),
+	}, nil
+}
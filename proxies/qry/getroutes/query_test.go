@@ -0,0 +1,49 @@
+package getroutes
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	q, err := NewQuery(&dummyTable{})
+
+	assert.NotNil(t, q)
+	assert.Nil(t, err)
+}
+
+func TestNewShouldReturnErrorOnMissingRouteTable(t *testing.T) {
+	q, err := NewQuery(nil)
+
+	assert.Nil(t, q)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, ErrMissingRouteTable, err)
+}
+
+func TestExecute(t *testing.T) {
+	u, _ := url.Parse("http://testapp")
+
+	q, _ := NewQuery(&dummyTable{
+		routes: []*interfaces.Route{
+			{FrontendName: "testapp", URL: u, ServiceName: "testapp"},
+		},
+	})
+
+	r, err := q.Execute(&Model{})
+
+	assert.Nil(t, err)
+	assert.Len(t, r.Routes, 1)
+	assert.Equal(t, "testapp", r.Routes[0].FrontendName)
+}
+
+type dummyTable struct {
+	routes []*interfaces.Route
+}
+
+func (t *dummyTable) Routes() []*interfaces.Route {
+	return t.routes
+}
@@ -0,0 +1,8 @@
+package getroutes
+
+import "github.com/off-sync/platform-proxy-app/interfaces"
+
+// Result specifies the output of the Query.
+type Result struct {
+	Routes []*interfaces.Route
+}
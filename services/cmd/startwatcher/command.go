@@ -0,0 +1,132 @@
+package startwatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrServiceWatcherMissing = errors.New("service watcher missing")
+	ErrEventsChannelMissing  = errors.New("events channel missing")
+)
+
+// Command models the Start Services Watcher Command which can be used to
+// watch for changes in the services configuration.
+type Command struct {
+	watcher interfaces.ServiceWatcher
+	logger  interfaces.Logger
+}
+
+// NewCommand creates a new Start Services Watcher Command using the provided
+// service watcher.
+func NewCommand(
+	watcher interfaces.ServiceWatcher,
+	logger interfaces.Logger) (*Command, error) {
+	if watcher == nil {
+		return nil, ErrServiceWatcherMissing
+	}
+
+	return &Command{
+		watcher: watcher,
+		logger:  logger,
+	}, nil
+}
+
+// Execute runs the Start Services Watcher Command by subscribing to the
+// service watcher and pushing its events to the provided channel.
+func (c *Command) Execute(model *Model) error {
+	if model.Events == nil {
+		return ErrEventsChannelMissing
+	}
+
+	if model.Ctx == nil {
+		model.Ctx = context.Background()
+	}
+
+	if model.WaitGroup == nil {
+		model.WaitGroup = &sync.WaitGroup{}
+	}
+
+	c.logger.Info("subscribing to service watcher")
+
+	events := c.watcher.Subscribe()
+
+	model.WaitGroup.Add(1)
+
+	go c.run(model.Ctx, model.WaitGroup, events, model.Events)
+
+	return nil
+}
+
+func (c *Command) run(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	in <-chan interfaces.ServiceEvent,
+	out chan<- Event) {
+	defer wg.Done()
+	defer c.watcher.Unsubscribe(in)
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("context is done: returning")
+			return
+
+		case serviceEvent, ok := <-in:
+			if !ok {
+				c.logger.Info("service watcher closed: returning")
+				return
+			}
+
+			event, err := c.translate(serviceEvent)
+			if err != nil {
+				c.logger.
+					WithError(err).
+					WithField("name", serviceEvent.Name).
+					Error("describing service")
+
+				break
+			}
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				c.logger.Info("context is done: returning")
+				return
+			}
+		}
+	}
+}
+
+func (c *Command) translate(serviceEvent interfaces.ServiceEvent) (Event, error) {
+	event := Event{
+		Name:     serviceEvent.Name,
+		Kind:     serviceEvent.Kind,
+		Revision: serviceEvent.Revision,
+		Service:  serviceEvent.Service,
+	}
+
+	// deleted events and events carrying a payload need no describe
+	if event.Kind == interfaces.EventDeleted || event.Service != nil {
+		return event, nil
+	}
+
+	service, err := c.watcher.DescribeService(serviceEvent.Name)
+	if err != nil {
+		if err == interfaces.ErrUnknownService {
+			event.Kind = interfaces.EventDeleted
+
+			return event, nil
+		}
+
+		return Event{}, err
+	}
+
+	event.Service = service
+
+	return event, nil
+}
@@ -0,0 +1,219 @@
+package startwatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/off-sync/platform-proxy-app/infra/logging"
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+var logger interfaces.Logger
+
+func init() {
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+
+	logger = logging.NewLogrusLogger(l)
+}
+
+func TestNewCommand(t *testing.T) {
+	c, err := NewCommand(newDummyWatcher(), logger)
+
+	assert.NotNil(t, c)
+	assert.Nil(t, err)
+}
+
+func TestNewCommandShouldReturnErrorOnMissingWatcher(t *testing.T) {
+	c, err := NewCommand(nil, logger)
+
+	assert.Nil(t, c)
+	assert.Equal(t, ErrServiceWatcherMissing, err)
+}
+
+func TestExecuteShouldReturnErrorOnMissingEventsChannel(t *testing.T) {
+	c, _ := NewCommand(newDummyWatcher(), logger)
+
+	err := c.Execute(&Model{})
+
+	assert.Equal(t, ErrEventsChannelMissing, err)
+}
+
+func TestExecute(t *testing.T) {
+	w := newDummyWatcher("testapp")
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	events := make(chan Event)
+
+	err := c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    events,
+	})
+
+	assert.Nil(t, err)
+
+	w.events <- interfaces.ServiceEvent{Name: "testapp"}
+
+	event := <-events
+	assert.Equal(t, "testapp", event.Name)
+	assert.NotNil(t, event.Service)
+
+	w.events <- interfaces.ServiceEvent{Name: "unknown"}
+
+	event = <-events
+	assert.Equal(t, "unknown", event.Name)
+	assert.Equal(t, interfaces.EventDeleted, event.Kind)
+	assert.Nil(t, event.Service)
+
+	// describe errors are logged and not forwarded
+	w.events <- interfaces.ServiceEvent{Name: "fail"}
+	w.events <- interfaces.ServiceEvent{Name: "testapp"}
+
+	event = <-events
+	assert.Equal(t, "testapp", event.Name)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestExecuteShouldForwardKindRevisionAndPayload(t *testing.T) {
+	w := newDummyWatcher()
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	events := make(chan Event)
+
+	c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    events,
+	})
+
+	payload := mockService("payload")
+
+	// the watcher does not know this service, so describing it would result
+	// in a deleted event
+	w.events <- interfaces.ServiceEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventCreated,
+		Revision: 7,
+		Service:  payload,
+	}
+
+	event := <-events
+	assert.Equal(t, interfaces.EventCreated, event.Kind)
+	assert.Equal(t, uint64(7), event.Revision)
+	assert.Equal(t, payload, event.Service)
+
+	w.events <- interfaces.ServiceEvent{
+		Name:     "payload",
+		Kind:     interfaces.EventDeleted,
+		Revision: 8,
+	}
+
+	event = <-events
+	assert.Equal(t, interfaces.EventDeleted, event.Kind)
+	assert.Equal(t, uint64(8), event.Revision)
+	assert.Nil(t, event.Service)
+
+	cancel()
+	wg.Wait()
+}
+
+func TestExecuteShouldStopWhenBlockedOnOutput(t *testing.T) {
+	w := newDummyWatcher("testapp")
+	c, _ := NewCommand(w, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	c.Execute(&Model{
+		Ctx:       ctx,
+		WaitGroup: wg,
+		Events:    make(chan Event),
+	})
+
+	w.events <- interfaces.ServiceEvent{Name: "testapp"}
+
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	wg.Wait()
+	assert.True(t, w.unsubscribed)
+}
+
+func TestExecuteShouldStopWhenWatcherIsClosed(t *testing.T) {
+	w := newDummyWatcher()
+	c, _ := NewCommand(w, logger)
+
+	wg := &sync.WaitGroup{}
+
+	c.Execute(&Model{
+		WaitGroup: wg,
+		Events:    make(chan Event),
+	})
+
+	close(w.events)
+
+	wg.Wait()
+}
+
+type dummyWatcher struct {
+	serviceNames []string
+	events       chan interfaces.ServiceEvent
+	unsubscribed bool
+}
+
+func newDummyWatcher(serviceNames ...string) *dummyWatcher {
+	return &dummyWatcher{
+		serviceNames: serviceNames,
+		events:       make(chan interfaces.ServiceEvent),
+	}
+}
+
+func (w *dummyWatcher) ListServices() ([]string, error) {
+	return w.serviceNames, nil
+}
+
+func (w *dummyWatcher) DescribeService(name string) (*services.Service, error) {
+	if name == "fail" {
+		return nil, errors.New("DescribeService(fail)")
+	}
+
+	for _, n := range w.serviceNames {
+		if name == n {
+			return mockService(n), nil
+		}
+	}
+
+	return nil, interfaces.ErrUnknownService
+}
+
+func (w *dummyWatcher) Subscribe() <-chan interfaces.ServiceEvent {
+	return w.events
+}
+
+func (w *dummyWatcher) Unsubscribe(events <-chan interfaces.ServiceEvent) {
+	w.unsubscribed = true
+}
+
+func mockService(name string) *services.Service {
+	s, err := services.NewService(name, "http://127.0.0.1:8080")
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return s
+}
@@ -0,0 +1,25 @@
+package startwatcher
+
+import (
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// Event contains the information related to a change in the services
+// configuration.
+type Event struct {
+	// Name of the service that changed.
+	Name string
+
+	// Kind specifies what happened to the service. Watchers that do not report
+	// the kind of change result in interfaces.EventChanged.
+	Kind interfaces.EventKind
+
+	// Revision is the revision reported by the watcher, or zero when the
+	// watcher does not track revisions.
+	Revision uint64
+
+	// Service holds the current configuration of the service. It is nil when
+	// the service has been deleted.
+	Service *services.Service
+}
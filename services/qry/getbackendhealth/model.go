@@ -0,0 +1,8 @@
+package getbackendhealth
+
+// Model specifies the input for the Query.
+type Model struct {
+	// ServiceName limits the result to the servers of a single service if
+	// set.
+	ServiceName string
+}
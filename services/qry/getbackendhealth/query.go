@@ -0,0 +1,50 @@
+package getbackendhealth
+
+import (
+	"errors"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+)
+
+// Errors
+var (
+	ErrMissingHealthReporter = errors.New("missing health reporter")
+)
+
+// Query implements the Get Backend Health Query. It requires a
+// HealthReporter, such as a load balancer that performs health checks.
+type Query struct {
+	reporter interfaces.HealthReporter
+}
+
+// NewQuery creates a new Get Backend Health Query
+func NewQuery(reporter interfaces.HealthReporter) (*Query, error) {
+	if reporter == nil {
+		return nil, ErrMissingHealthReporter
+	}
+
+	return &Query{
+		reporter: reporter,
+	}, nil
+}
+
+// Execute performs the Get Backend Health Query using the provided model.
+func (q *Query) Execute(model *Model) (*Result, error) {
+	backends := q.reporter.BackendHealth()
+
+	if model.ServiceName != "" {
+		var filtered []*interfaces.BackendHealth
+
+		for _, b := range backends {
+			if b.ServiceName == model.ServiceName {
+				filtered = append(filtered, b)
+			}
+		}
+
+		backends = filtered
+	}
+
+	return &Result{
+		Backends: backends,
+	}, nil
+}
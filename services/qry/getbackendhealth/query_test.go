@@ -0,0 +1,66 @@
+package getbackendhealth
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	q, err := NewQuery(&dummyReporter{})
+
+	assert.NotNil(t, q)
+	assert.Nil(t, err)
+}
+
+func TestNewShouldReturnErrorOnMissingHealthReporter(t *testing.T) {
+	q, err := NewQuery(nil)
+
+	assert.Nil(t, q)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, ErrMissingHealthReporter, err)
+}
+
+func TestExecute(t *testing.T) {
+	q, _ := NewQuery(newDummyReporter())
+
+	r, err := q.Execute(&Model{})
+
+	assert.Nil(t, err)
+	assert.Len(t, r.Backends, 3)
+}
+
+func TestExecuteShouldFilterOnServiceName(t *testing.T) {
+	q, _ := NewQuery(newDummyReporter())
+
+	r, err := q.Execute(&Model{ServiceName: "api"})
+
+	assert.Nil(t, err)
+	assert.Len(t, r.Backends, 2)
+	assert.False(t, r.Backends[1].Healthy)
+	assert.NotNil(t, r.Backends[1].LastError)
+}
+
+type dummyReporter struct {
+	backends []*interfaces.BackendHealth
+}
+
+func newDummyReporter() *dummyReporter {
+	u, _ := url.Parse("http://10.0.0.1:8080")
+
+	return &dummyReporter{
+		backends: []*interfaces.BackendHealth{
+			{ServiceName: "api", URL: u, Healthy: true, Checked: true},
+			{ServiceName: "api", URL: u, Checked: true, LastError: errors.New("connection refused")},
+			{ServiceName: "web", URL: u, Healthy: true},
+		},
+	}
+}
+
+func (r *dummyReporter) BackendHealth() []*interfaces.BackendHealth {
+	return r.backends
+}
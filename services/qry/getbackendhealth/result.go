@@ -0,0 +1,8 @@
+package getbackendhealth
+
+import "github.com/off-sync/platform-proxy-app/interfaces"
+
+// Result specifies the output of the Query.
+type Result struct {
+	Backends []*interfaces.BackendHealth
+}
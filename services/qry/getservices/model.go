@@ -0,0 +1,5 @@
+package getservices
+
+// Model specifies the input for the Query.
+type Model struct {
+}
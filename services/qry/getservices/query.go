@@ -0,0 +1,50 @@
+package getservices
+
+import (
+	"errors"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+)
+
+// Errors
+var (
+	ErrMissingServiceRepository = errors.New("missing services repository")
+)
+
+// Query implements the Get Services Query. It requires a ServiceRepository.
+type Query struct {
+	repo interfaces.ServiceRepository
+}
+
+// NewQuery creates a new Get Services Query
+func NewQuery(repo interfaces.ServiceRepository) (*Query, error) {
+	if repo == nil {
+		return nil, ErrMissingServiceRepository
+	}
+
+	return &Query{
+		repo: repo,
+	}, nil
+}
+
+// Execute performs the Get Services Query using the provided model.
+func (q *Query) Execute(model *Model) (*Result, error) {
+	names, err := q.repo.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Services: make([]*services.Service, len(names)),
+	}
+
+	for i, name := range names {
+		result.Services[i], err = q.repo.DescribeService(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
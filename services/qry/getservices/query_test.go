@@ -0,0 +1,98 @@
+package getservices
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/off-sync/platform-proxy-app/interfaces"
+	"github.com/off-sync/platform-proxy-domain/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	q, err := NewQuery(&dummyRepo{})
+
+	assert.NotNil(t, q)
+	assert.Nil(t, err)
+}
+
+func TestNewShouldReturnErrorOnMissingRepository(t *testing.T) {
+	q, err := NewQuery(nil)
+
+	assert.Nil(t, q)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, ErrMissingServiceRepository, err)
+}
+
+func TestExecute(t *testing.T) {
+	q, _ := NewQuery(&dummyRepo{
+		serviceNames: []string{"test1", "test2"},
+	})
+
+	r, err := q.Execute(&Model{})
+
+	assert.NotNil(t, r)
+	assert.Nil(t, err)
+
+	assert.Len(t, r.Services, 2)
+	assert.Equal(t, "test1", r.Services[0].Name)
+	assert.Equal(t, "test2", r.Services[1].Name)
+}
+
+func TestExecuteDescribeServiceErrorShouldBeReturned(t *testing.T) {
+	q, _ := NewQuery(&dummyRepo{
+		serviceNames: []string{"unknown"},
+	})
+
+	r, err := q.Execute(&Model{})
+
+	assert.Nil(t, r)
+	assert.Equal(t, interfaces.ErrUnknownService, err)
+}
+
+func TestExecuteShouldReturnErrorFromRepository(t *testing.T) {
+	q, _ := NewQuery(&dummyRepo{})
+
+	r, err := q.Execute(&Model{})
+
+	assert.Nil(t, r)
+	assert.NotNil(t, err)
+}
+
+type dummyRepo struct {
+	serviceNames []string
+}
+
+func (r *dummyRepo) ListServices() ([]string, error) {
+	if len(r.serviceNames) < 1 {
+		// return error in case the list is empty
+		return nil, errors.New("no services configured")
+	}
+
+	return r.serviceNames, nil
+}
+
+func (r *dummyRepo) DescribeService(name string) (*services.Service, error) {
+	if name == "unknown" {
+		return nil, interfaces.ErrUnknownService
+	}
+
+	for _, n := range r.serviceNames {
+		if name == n {
+			return mockService(n), nil
+		}
+	}
+
+	return nil, interfaces.ErrUnknownService
+}
+
+func mockService(name string) *services.Service {
+	s, err := services.NewService(name, "http://"+name+":8080")
+	if err != nil {
+		// should not happen
+		panic(err)
+	}
+
+	return s
+}
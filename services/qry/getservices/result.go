@@ -0,0 +1,8 @@
+package getservices
+
+import "github.com/off-sync/platform-proxy-domain/services"
+
+// Result specifies the output of the Query.
+type Result struct {
+	Services []*services.Service
+}